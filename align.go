@@ -0,0 +1,285 @@
+package mglda
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// hungarian returns the assignment of the rows of cost to distinct columns
+// with the least total cost (Kuhn and Munkres, with potentials). Rows left
+// over when cost has more rows than columns are assigned -1.
+func hungarian(cost [][]float64) []int {
+	rows := len(cost)
+	cols := 0
+	if rows > 0 {
+		cols = len(cost[0])
+	}
+	n := rows
+	if cols > n {
+		n = cols
+	}
+	// a square matrix padded with zero cost, indexed from 1
+	c := func(i, j int) float64 {
+		if i <= rows && j <= cols {
+			return cost[i-1][j-1]
+		}
+		return 0
+	}
+	u := make([]float64, n+1)
+	v := make([]float64, n+1)
+	p := make([]int, n+1) // the row assigned to column j
+	way := make([]int, n+1)
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, n+1)
+		used := make([]bool, n+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		for {
+			used[j0] = true
+			i0, delta, j1 := p[j0], math.Inf(1), 0
+			for j := 1; j <= n; j++ {
+				if used[j] {
+					continue
+				}
+				if cur := c(i0, j) - u[i0] - v[j]; cur < minv[j] {
+					minv[j], way[j] = cur, j0
+				}
+				if minv[j] < delta {
+					delta, j1 = minv[j], j
+				}
+			}
+			for j := 0; j <= n; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+	assignment := make([]int, rows)
+	for i := range assignment {
+		assignment[i] = -1
+	}
+	for j := 1; j <= cols; j++ {
+		if p[j] >= 1 && p[j] <= rows {
+			assignment[p[j]-1] = j - 1
+		}
+	}
+	return assignment
+}
+
+// alignTopics matches every topic word distribution a[i] to a distinct one
+// of b by the least total Jensen-Shannon distance, the square root of the
+// divergence. It returns the matched row of b for every row of a, -1 if b
+// has fewer rows, and the distance of every match, 1 if unmatched.
+func alignTopics(a, b [][]float64) ([]int, []float64) {
+	cost := make([][]float64, len(a))
+	for i := range a {
+		cost[i] = make([]float64, len(b))
+		for j := range b {
+			cost[i][j] = math.Sqrt(JensenShannon(a[i], b[j]))
+		}
+	}
+	match := hungarian(cost)
+	dist := make([]float64, len(a))
+	for i, j := range match {
+		dist[i] = 1
+		if j >= 0 {
+			dist[i] = cost[i][j]
+		}
+	}
+	return match, dist
+}
+
+// TopicMatch is the topic of a second model matched to a topic of a first
+// one. Distance is the Jensen-Shannon distance of their word distributions,
+// in [0, 1], and Overlap the Jaccard similarity of their top words; an
+// unmatched topic has Match -1, Distance 1 and Overlap 0.
+type TopicMatch struct {
+	Kind      TopicKind `json:"kind"`
+	Topic     int       `json:"topic"`
+	Name      string    `json:"name,omitempty"`
+	Match     int       `json:"match"`
+	MatchName string    `json:"match_name,omitempty"`
+	Distance  float64   `json:"distance"`
+	Overlap   float64   `json:"overlap"`
+}
+
+// Alignment matches every global and then every local topic of a model to
+// a distinct topic of the same kind of another one. Similarity is the mean
+// of one minus the distance of every match.
+type Alignment struct {
+	Topics     []TopicMatch `json:"topics"`
+	Similarity float64      `json:"similarity"`
+}
+
+// wordDists returns the global and local topic word distributions of m as
+// in WordDist, with every row scaled to sum to one.
+func (m *MGLDA) wordDists() ([][]float64, [][]float64) {
+	phiGl, phiLoc := m.WordDist()
+	gl, loc := phiGl.Arrays(), phiLoc.Arrays()
+	for z := range gl {
+		gl[z] = normalizeRow(gl[z])
+	}
+	for z := range loc {
+		loc[z] = normalizeRow(loc[z])
+	}
+	return gl, loc
+}
+
+// commonWordDists returns the global and then the local topic word
+// distributions of a and then b over the union of their vocabularies va and
+// vb, keyed by word. Without both vocabularies the word ids must agree.
+func commonWordDists(a, b *MGLDA, va, vb []string) ([2][2][][]float64, error) {
+	var kinds [2][2][][]float64
+	phiA := [2][][]float64{}
+	phiB := [2][][]float64{}
+	phiA[0], phiA[1] = a.wordDists()
+	phiB[0], phiB[1] = b.wordDists()
+	if len(va) == 0 || len(vb) == 0 {
+		if a.W != b.W {
+			return kinds, fmt.Errorf("align: models of %d and %d words need their vocabularies", a.W, b.W)
+		}
+		return [2][2][][]float64{{phiA[0], phiB[0]}, {phiA[1], phiB[1]}}, nil
+	}
+	v := NewVocabulary(va)
+	idsA, idsB := make([]int, a.W), make([]int, b.W)
+	for w := range idsA {
+		idsA[w] = v.Add(wordLabel(va, w))
+	}
+	for w := range idsB {
+		idsB[w] = v.Add(wordLabel(vb, w))
+	}
+	remap := func(rows [][]float64, ids []int) [][]float64 {
+		out := make([][]float64, len(rows))
+		for z, row := range rows {
+			out[z] = make([]float64, v.Len())
+			for w, p := range row {
+				out[z][ids[w]] += p
+			}
+		}
+		return out
+	}
+	for k := range kinds {
+		kinds[k] = [2][][]float64{remap(phiA[k], idsA), remap(phiB[k], idsB)}
+	}
+	return kinds, nil
+}
+
+// topIDs returns the ids of the n largest entries of row.
+func topIDs(row []float64, n int) []int {
+	ids := make([]int, len(row))
+	for i := range ids {
+		ids[i] = i
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return row[ids[i]] > row[ids[j]] })
+	if n < len(ids) {
+		ids = ids[:n]
+	}
+	return ids
+}
+
+// AlignModels matches the global and local topics of b to those of a by
+// the Jensen-Shannon distance of their word distributions (Hungarian
+// matching), and compares the top n words of every match. The words of
+// the models are matched by the vocabularies va and vb if both are given,
+// and by id otherwise.
+func AlignModels(a, b *MGLDA, va, vb []string, n int) (*Alignment, error) {
+	kinds, err := commonWordDists(a, b, va, vb)
+	if err != nil {
+		return nil, err
+	}
+	al := &Alignment{Topics: []TopicMatch{}}
+	var similarity float64
+	for k, kind := range []TopicKind{Global, Local} {
+		phiA, phiB := kinds[k][0], kinds[k][1]
+		match, dist := alignTopics(phiA, phiB)
+		for z, j := range match {
+			tm := TopicMatch{Kind: kind, Topic: z, Name: a.Labels.Name(kind, z), Match: j, Distance: dist[z]}
+			if j >= 0 {
+				tm.MatchName = b.Labels.Name(kind, j)
+				tm.Overlap = Jaccard(topIDs(phiA[z], n), topIDs(phiB[j], n))
+			}
+			similarity += 1 - tm.Distance
+			al.Topics = append(al.Topics, tm)
+		}
+	}
+	if len(al.Topics) > 0 {
+		al.Similarity = similarity / float64(len(al.Topics))
+	}
+	return al, nil
+}
+
+// TopicStability is the mean similarity, one minus the Jensen-Shannon
+// distance, of a topic of the first model to its matches in the others.
+type TopicStability struct {
+	Kind       TopicKind `json:"kind"`
+	Topic      int       `json:"topic"`
+	Name       string    `json:"name,omitempty"`
+	Similarity float64   `json:"similarity"`
+}
+
+// StabilityReport compares models trained with different seeds. Score is
+// the mean Alignment.Similarity over all pairs of models, 1 if they found
+// the same topics.
+type StabilityReport struct {
+	Models int              `json:"models"`
+	Pairs  int              `json:"pairs"`
+	Score  float64          `json:"score"`
+	Topics []TopicStability `json:"topics"`
+	// Alignments holds the alignment of every other model to the first.
+	Alignments []*Alignment `json:"alignments"`
+}
+
+// Stability aligns every pair of models, with vocabularies as in
+// AlignModels (nil or one per model), and reports the stability of their
+// topics.
+func Stability(models []*MGLDA, vocabularies [][]string, n int) (*StabilityReport, error) {
+	if len(models) < 2 {
+		return nil, fmt.Errorf("align: %d models, want at least 2", len(models))
+	}
+	vocabulary := func(i int) []string {
+		if vocabularies == nil {
+			return nil
+		}
+		return vocabularies[i]
+	}
+	r := &StabilityReport{Models: len(models)}
+	for i := range models {
+		for j := i + 1; j < len(models); j++ {
+			al, err := AlignModels(models[i], models[j], vocabulary(i), vocabulary(j), n)
+			if err != nil {
+				return nil, err
+			}
+			r.Score += al.Similarity
+			r.Pairs++
+			if i == 0 {
+				r.Alignments = append(r.Alignments, al)
+			}
+		}
+	}
+	r.Score /= float64(r.Pairs)
+	for t, tm := range r.Alignments[0].Topics {
+		ts := TopicStability{Kind: tm.Kind, Topic: tm.Topic, Name: tm.Name}
+		for _, al := range r.Alignments {
+			ts.Similarity += (1 - al.Topics[t].Distance) / float64(len(r.Alignments))
+		}
+		r.Topics = append(r.Topics, ts)
+	}
+	return r, nil
+}
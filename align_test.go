@@ -0,0 +1,104 @@
+package mglda
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlignModels(t *testing.T) {
+	d := reviews(20)
+	a := NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d)
+	a.Train(10)
+	va := []string{"good", "bad", "w2", "w3", "w4", "w5"}
+	assert.Nil(t, a.SetLabels(TopicLabels{{Kind: Local, Topic: 1, Name: "praise"}}))
+
+	// b has the topics of a in the other order, over reversed word ids
+	d2 := []Document{}
+	for _, doc := range d {
+		doc2 := Document{}
+		for _, sent := range doc.Sentenses {
+			words := []int{}
+			for _, wd := range sent.Words {
+				words = append(words, 5-wd)
+			}
+			doc2.Sentenses = append(doc2.Sentenses, Sentense{Words: words})
+		}
+		d2 = append(d2, doc2)
+	}
+	vb := []string{"w5", "w4", "w3", "w2", "bad", "good"}
+	b := newMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d2)
+	for dd := range d2 {
+		b.allocDocument(dd)
+		for s := range d2[dd].Sentenses {
+			for w := range d2[dd].Sentenses[s].Words {
+				b.Vdsn[dd][s][w] = a.Vdsn[dd][s][w]
+				b.Rdsn[dd][s][w] = a.Rdsn[dd][s][w]
+				b.Zdsn[dd][s][w] = 1 - a.Zdsn[dd][s][w]
+			}
+		}
+		b.loadDocument(dd)
+	}
+
+	al, err := AlignModels(a, b, va, vb, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(al.Topics))
+	for i, tm := range al.Topics {
+		assert.Equal(t, 1-tm.Topic, tm.Match)
+		assert.InDelta(t, 0, tm.Distance, 1e-6)
+		assert.Equal(t, 1.0, tm.Overlap, "topic %d", i)
+	}
+	assert.Equal(t, "praise", al.Topics[3].Name)
+	assert.InDelta(t, 1, al.Similarity, 1e-6)
+
+	// without vocabularies the ids are compared as they are
+	al, err = AlignModels(a, b, nil, nil, 3)
+	assert.Nil(t, err)
+	assert.True(t, al.Similarity < 1)
+	small := NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 4, &[]Document{})
+	_, err = AlignModels(a, small, nil, nil, 3)
+	assert.NotNil(t, err)
+
+	// a model with fewer topics leaves topics unmatched
+	d3 := reviews(20)
+	c := NewMGLDA(1, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d3)
+	c.Train(10)
+	al, err = AlignModels(a, c, nil, nil, 3)
+	assert.Nil(t, err)
+	unmatched := 0
+	for _, tm := range al.Topics {
+		if tm.Match < 0 {
+			unmatched++
+			assert.Equal(t, 1.0, tm.Distance)
+		}
+	}
+	assert.Equal(t, 1, unmatched)
+
+	_, err = Stability([]*MGLDA{a}, nil, 3)
+	assert.NotNil(t, err)
+	r, err := Stability([]*MGLDA{a, b, c}, [][]string{va, vb, va}, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, r.Pairs)
+	assert.Equal(t, 2, len(r.Alignments))
+	assert.Equal(t, 4, len(r.Topics))
+	assert.True(t, r.Score > 0 && r.Score < 1)
+	for _, ts := range r.Topics {
+		assert.True(t, ts.Similarity >= 0 && ts.Similarity <= 1)
+	}
+}
+
+func TestAlignCounts(t *testing.T) {
+	// the same word distribution from different numbers of words
+	a := NewMGLDA(1, 1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 3, &[]Document{})
+	b := NewMGLDA(1, 1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 3, &[]Document{})
+	for w, n := range []float64{2, 1, 1} {
+		a.Nglzw.Set(0, w, n)
+		b.Nglzw.Set(0, w, 2*n)
+	}
+	a.Nglz.Set(0, 0, 4)
+	b.Nglz.Set(0, 0, 8)
+	al, err := AlignModels(a, b, nil, nil, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.0, al.Topics[0].Distance)
+	assert.Equal(t, 1.0, al.Similarity)
+}
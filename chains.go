@@ -0,0 +1,274 @@
+package mglda
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Chains runs several independent Gibbs chains of the same model, to tell
+// from their agreement whether sampling has converged.
+type Chains struct {
+	Models []*MGLDA
+	// Shares holds, for every chain and sweep, the share of the counted
+	// words assigned to every global and then every local topic.
+	Shares [][][]float64
+}
+
+// NewChains returns n chains of m, each a copy of m with its own random
+// source, seeded by seed plus its index, from which all words are assigned
+// at random again. m is not changed. Chains with an HDP are not supported,
+// as their topics could not be matched.
+func NewChains(m *MGLDA, n int, seed int64) (*Chains, error) {
+	if n < 2 {
+		return nil, fmt.Errorf("chains: %d chains, want at least 2", n)
+	}
+	if m.HDP != nil {
+		return nil, fmt.Errorf("chains: not supported with an hdp")
+	}
+	var buf bytes.Buffer
+	if err := SaveModel(&buf, m, nil); err != nil {
+		return nil, err
+	}
+	saved := buf.Bytes()
+	c := &Chains{Models: make([]*MGLDA, n), Shares: make([][][]float64, n)}
+	for i := range c.Models {
+		chain, _, err := LoadModel(bytes.NewReader(saved))
+		if err != nil {
+			return nil, err
+		}
+		chain.SetSeed(seed + int64(i))
+		chain.Trace = nil
+		chain.Randomize()
+		c.Models[i] = chain
+	}
+	return c, nil
+}
+
+// Randomize assigns every word of every document to a window and topic
+// uniformly at random again, for example from the source set by SetSeed.
+func (m *MGLDA) Randomize() {
+	r := m.random()
+	for d, doc := range *m.Docs {
+		if doc.State != Holdout {
+			m.unloadDocument(d)
+		}
+		for s, sts := range doc.Sentenses {
+			for w := range sts.Words {
+				m.Vdsn[d][s][w] = r.Intn(m.window(d))
+				if r.Intn(2) == 0 {
+					m.Rdsn[d][s][w] = globalTopic
+					m.Zdsn[d][s][w] = r.Intn(m.GlobalK)
+				} else {
+					m.Rdsn[d][s][w] = localTopic
+					m.Zdsn[d][s][w] = r.Intn(m.LocalK)
+				}
+			}
+		}
+		if doc.State != Holdout {
+			m.loadDocument(d)
+		}
+	}
+	if m.Ratings != nil {
+		m.Ratings.refresh(m)
+	}
+}
+
+// shares returns the share of the counted words assigned to every global
+// and then every local topic.
+func (m *MGLDA) shares() []float64 {
+	x := make([]float64, 0, m.GlobalK+m.LocalK)
+	for z := 0; z < m.GlobalK; z++ {
+		x = append(x, m.Nglz.Get(z, 0))
+	}
+	for z := 0; z < m.LocalK; z++ {
+		x = append(x, m.Nlocz.Get(z, 0))
+	}
+	return normalizeRow(x)
+}
+
+// Train runs iteration sweeps of Train on every chain concurrently and
+// records the topic shares after every sweep.
+func (c *Chains) Train(iteration int) {
+	var wg sync.WaitGroup
+	for i, m := range c.Models {
+		wg.Add(1)
+		go func(i int, m *MGLDA) {
+			defer wg.Done()
+			for it := 0; it < iteration; it++ {
+				m.Train(1)
+				c.Shares[i] = append(c.Shares[i], m.shares())
+			}
+		}(i, m)
+	}
+	wg.Wait()
+}
+
+// Align matches the topics of every chain to those of the first chain by
+// their word distributions. It returns, for every chain, the matched topic
+// of the chain for every global and then every local topic of the first
+// chain, and the Jensen-Shannon distance of every match. The word
+// distributions are normalized, so that topics of different sizes compare.
+func (c *Chains) Align() ([][]int, [][]float64) {
+	refGl, refLoc := c.Models[0].wordDists()
+	matches := make([][]int, len(c.Models))
+	distances := make([][]float64, len(c.Models))
+	for i, m := range c.Models {
+		phiGl, phiLoc := m.wordDists()
+		gl, jsGl := alignTopics(refGl, phiGl)
+		loc, jsLoc := alignTopics(refLoc, phiLoc)
+		for z := range loc {
+			loc[z] += m.GlobalK
+		}
+		matches[i] = append(gl, loc...)
+		distances[i] = append(jsGl, jsLoc...)
+	}
+	return matches, distances
+}
+
+// Convergence holds the potential scale reduction factor (R-hat) of a
+// quantity over the chains, near 1 once they agree, and its effective
+// sample size over all chains.
+type Convergence struct {
+	RHat float64 `json:"rhat"`
+	ESS  float64 `json:"ess"`
+}
+
+// TopicConvergence is the convergence of the word share of a topic of the
+// first chain and its matches in the other chains. JS is the mean
+// Jensen-Shannon distance of the matches from the topic.
+type TopicConvergence struct {
+	Kind  TopicKind `json:"kind"`
+	Topic int       `json:"topic"`
+	Name  string    `json:"name,omitempty"`
+	Convergence
+	JS float64 `json:"js"`
+}
+
+// ChainDiagnostics reports the convergence of the log-likelihood and of
+// every aligned topic over the draws after burnin.
+type ChainDiagnostics struct {
+	Chains        int                `json:"chains"`
+	Draws         int                `json:"draws"`
+	LogLikelihood Convergence        `json:"loglikelihood"`
+	Topics        []TopicConvergence `json:"topics"`
+	// MaxRHat is the largest R-hat of all quantities.
+	MaxRHat float64 `json:"max_rhat"`
+}
+
+// Diagnose computes the convergence diagnostics of the chains after
+// discarding the first burnin sweeps, with the topics aligned by Align.
+func (c *Chains) Diagnose(burnin int) ChainDiagnostics {
+	draws := len(c.Shares[0])
+	for _, shares := range c.Shares {
+		if len(shares) < draws {
+			draws = len(shares)
+		}
+	}
+	if burnin > draws {
+		burnin = draws
+	}
+	diag := ChainDiagnostics{Chains: len(c.Models), Draws: draws - burnin}
+
+	traces := make([][]float64, len(c.Models))
+	for i, m := range c.Models {
+		traces[i] = m.Trace[len(m.Trace)-draws+burnin:]
+	}
+	diag.LogLikelihood = convergence(traces)
+	diag.MaxRHat = diag.LogLikelihood.RHat
+
+	matches, distances := c.Align()
+	ref := c.Models[0]
+	for t := 0; t < ref.GlobalK+ref.LocalK; t++ {
+		kind, z := Global, t
+		if t >= ref.GlobalK {
+			kind, z = Local, t-ref.GlobalK
+		}
+		series := make([][]float64, len(c.Models))
+		var js float64
+		for i := range c.Models {
+			series[i] = make([]float64, 0, draws-burnin)
+			for _, shares := range c.Shares[i][burnin:draws] {
+				series[i] = append(series[i], shares[matches[i][t]])
+			}
+			if i > 0 {
+				js += distances[i][t] / float64(len(c.Models)-1)
+			}
+		}
+		tc := TopicConvergence{Kind: kind, Topic: z, Name: ref.Labels.Name(kind, z),
+			Convergence: convergence(series), JS: js}
+		diag.Topics = append(diag.Topics, tc)
+		diag.MaxRHat = math.Max(diag.MaxRHat, tc.RHat)
+	}
+	return diag
+}
+
+// convergence returns the R-hat (Gelman and Rubin, 1992) and the effective
+// sample size of draws of equal length from several chains. The effective
+// sample size sums the autocorrelations pooled over the chains in pairs
+// while their sum is positive (Geyer, 1992), as in Stan.
+func convergence(chains [][]float64) Convergence {
+	m, n := float64(len(chains)), 0
+	if len(chains) > 0 {
+		n = len(chains[0])
+	}
+	if n < 2 {
+		return Convergence{RHat: math.NaN(), ESS: 0}
+	}
+	means := make([]float64, len(chains))
+	var mean, w float64
+	for j, x := range chains {
+		means[j] = meanOf(x)
+		mean += means[j] / m
+		var ss float64
+		for _, v := range x {
+			ss += (v - means[j]) * (v - means[j])
+		}
+		w += ss / float64(n-1) / m
+	}
+	var b float64
+	for _, mu := range means {
+		b += (mu - mean) * (mu - mean)
+	}
+	b *= float64(n) / (m - 1)
+	varPlus := float64(n-1)/float64(n)*w + b/float64(n)
+	if w == 0 {
+		if b == 0 {
+			return Convergence{RHat: 1, ESS: m * float64(n)}
+		}
+		return Convergence{RHat: math.Inf(1), ESS: 0}
+	}
+	result := Convergence{RHat: math.Sqrt(varPlus / w)}
+
+	// rho returns the autocorrelation at lag t pooled over the chains
+	rho := func(t int) float64 {
+		var acov float64
+		for j, x := range chains {
+			var s float64
+			for i := 0; i+t < n; i++ {
+				s += (x[i] - means[j]) * (x[i+t] - means[j])
+			}
+			acov += s / float64(n) / m
+		}
+		return 1 - (w-acov)/varPlus
+	}
+	tau := -1.0
+	for t := 0; t+1 < n; t += 2 {
+		pair := rho(t) + rho(t+1)
+		if pair <= 0 {
+			break
+		}
+		tau += 2 * pair
+	}
+	result.ESS = m * float64(n) / math.Max(tau, 1/math.Log10(m*float64(n)))
+	return result
+}
+
+func meanOf(x []float64) float64 {
+	var s float64
+	for _, v := range x {
+		s += v
+	}
+	return s / float64(len(x))
+}
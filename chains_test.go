@@ -0,0 +1,94 @@
+package mglda
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHungarian(t *testing.T) {
+	cost := [][]float64{{4, 1, 3}, {2, 0, 5}, {3, 2, 2}}
+	assert.Equal(t, []int{1, 0, 2}, hungarian(cost))
+	assert.Equal(t, []int{1, 0}, hungarian([][]float64{{4, 1, 3}, {2, 0, 5}}))
+	assert.Equal(t, []int{-1, 0, 1}, hungarian([][]float64{{9, 9}, {1, 5}, {5, 1}}))
+
+	ref := [][]float64{{0.9, 0.1, 0}, {0, 0.1, 0.9}}
+	other := [][]float64{{0, 0.2, 0.8}, {0.8, 0.2, 0}}
+	match, js := alignTopics(ref, other)
+	assert.Equal(t, []int{1, 0}, match)
+	assert.True(t, js[0] < 0.2 && js[1] < 0.2)
+}
+
+func TestConvergence(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	same := make([][]float64, 4)
+	apart := make([][]float64, 4)
+	for j := range same {
+		for i := 0; i < 500; i++ {
+			same[j] = append(same[j], r.NormFloat64())
+			apart[j] = append(apart[j], r.NormFloat64()+float64(3*j))
+		}
+	}
+	c := convergence(same)
+	assert.InDelta(t, 1, c.RHat, 0.02)
+	assert.True(t, c.ESS > 1000 && c.ESS <= 2000*math.Log10(2000))
+	assert.True(t, convergence(apart).RHat > 2)
+
+	// an autocorrelated chain has fewer effective draws
+	walk := make([][]float64, 2)
+	for j := range walk {
+		x := 0.0
+		for i := 0; i < 500; i++ {
+			x = 0.95*x + r.NormFloat64()
+			walk[j] = append(walk[j], x)
+		}
+	}
+	assert.True(t, convergence(walk).ESS < 200)
+	assert.Equal(t, 1.0, convergence([][]float64{{1, 1}, {1, 1}}).RHat)
+}
+
+func TestChains(t *testing.T) {
+	d := reviews(20)
+	m := NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d)
+	m.Train(1)
+	z := m.Zdsn[0][0][0]
+	_, err := NewChains(m, 1, 1)
+	assert.NotNil(t, err)
+
+	c, err := NewChains(m, 3, 7)
+	assert.Nil(t, err)
+	c.Train(30)
+	assert.Equal(t, z, m.Zdsn[0][0][0])
+	assert.Equal(t, 1, len(m.Trace))
+	for i, chain := range c.Models {
+		assert.Equal(t, 30, len(chain.Trace))
+		assert.Equal(t, 30, len(c.Shares[i]))
+		assert.InDelta(t, 1, sum(c.Shares[i][29]), 1e-9)
+	}
+
+	matches, distances := c.Align()
+	assert.Equal(t, []int{0, 1, 2, 3}, matches[0])
+	assert.Equal(t, []float64{0, 0, 0, 0}, distances[0])
+	for _, dist := range distances {
+		for _, x := range dist {
+			assert.True(t, x >= 0 && x <= 1)
+		}
+	}
+	diag := c.Diagnose(10)
+	assert.Equal(t, 3, diag.Chains)
+	assert.Equal(t, 20, diag.Draws)
+	assert.Equal(t, 4, len(diag.Topics))
+	assert.Equal(t, Local, diag.Topics[2].Kind)
+	assert.True(t, diag.LogLikelihood.ESS > 0)
+	assert.True(t, diag.MaxRHat >= diag.LogLikelihood.RHat)
+
+	// the chains are reproducible from their seed
+	again, _ := NewChains(m, 3, 7)
+	again.Train(30)
+	for i := range c.Models {
+		assert.Equal(t, c.Models[i].Zdsn, again.Models[i].Zdsn)
+	}
+	assert.NotEqual(t, c.Models[0].Zdsn, c.Models[1].Zdsn)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/yuui-ro/mglda"
+)
+
+// aggregate writes the mean topic distributions of the documents of the
+// model in model_path per value of a metadata field.
+func aggregate(args []string) {
+	var field string
+	conf := parseArgs("aggregate", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&field, "field", "", "Metadata field to group the documents by")
+	})
+	check(conf.validate())
+	if field == "" {
+		check(fmt.Errorf("aggregate: -field is required"))
+	}
+
+	m, _ := loadModel(conf.ModelPath)
+	groups := m.AggregateByMeta(field)
+
+	out := createOutput(conf.OutPath)
+	defer checkClose(out)
+	wt := bufio.NewWriter(out)
+	defer wt.Flush()
+	switch conf.OutputFormat {
+	case "json":
+		enc := json.NewEncoder(wt)
+		enc.SetIndent("", "  ")
+		check(enc.Encode(groups))
+	case "tsv":
+		check(mglda.WriteMetaGroupsCSV(wt, groups, m.Labels, '\t'))
+	default:
+		check(mglda.WriteMetaGroupsCSV(wt, groups, m.Labels, ','))
+	}
+}
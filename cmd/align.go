@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yuui-ro/mglda"
+)
+
+// align matches the topics of the models in others to those of the model
+// in model_path and reports how stable the topics are across them.
+func align(args []string) {
+	var others string
+	conf := parseArgs("align", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&others, "others", "", "Comma-separated paths of the models to align to the model in model_path")
+	})
+	check(conf.validate())
+	if others == "" {
+		check(fmt.Errorf("align: -others is required"))
+	}
+	if conf.OutputFormat != "text" && conf.OutputFormat != "json" {
+		check(fmt.Errorf("align: output_format must be text or json"))
+	}
+
+	m, vocabulary := loadModel(conf.ModelPath)
+	models := []*mglda.MGLDA{m}
+	vocabularies := [][]string{vocabulary}
+	for _, fn := range strings.Split(others, ",") {
+		other, v := loadModel(fn)
+		models = append(models, other)
+		vocabularies = append(vocabularies, v)
+	}
+	report, err := mglda.Stability(models, vocabularies, conf.TopN)
+	check(err)
+
+	out := createOutput(conf.OutPath)
+	defer checkClose(out)
+	wt := bufio.NewWriter(out)
+	defer wt.Flush()
+	if conf.OutputFormat == "json" {
+		enc := json.NewEncoder(wt)
+		enc.SetIndent("", "  ")
+		check(enc.Encode(report))
+		return
+	}
+	writeStability(wt, report)
+}
+
+func writeStability(wt io.Writer, report *mglda.StabilityReport) {
+	named := func(topic int, name string) string {
+		if name == "" {
+			return fmt.Sprint(topic)
+		}
+		return fmt.Sprintf("%d [%s]", topic, name)
+	}
+	for i, al := range report.Alignments {
+		fmt.Fprintf(wt, "==== model %d: similarity %.3f ====\n", i+1, al.Similarity)
+		for _, t := range al.Topics {
+			if t.Match < 0 {
+				fmt.Fprintf(wt, "%s topic %s: unmatched\n", t.Kind, named(t.Topic, t.Name))
+				continue
+			}
+			fmt.Fprintf(wt, "%s topic %s -> %s: distance %.3f, overlap %.3f\n",
+				t.Kind, named(t.Topic, t.Name), named(t.Match, t.MatchName), t.Distance, t.Overlap)
+		}
+	}
+	fmt.Fprintf(wt, "==== stability of %d models ====\n", report.Models)
+	for _, t := range report.Topics {
+		fmt.Fprintf(wt, "%s topic %s: %.3f\n", t.Kind, named(t.Topic, t.Name), t.Similarity)
+	}
+	fmt.Fprintf(wt, "score: %.3f over %d pairs\n", report.Score, report.Pairs)
+}
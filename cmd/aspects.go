@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+
+	"github.com/yuui-ro/mglda"
+)
+
+// aspects writes the most representative sentences of every local topic of
+// the model in model_path.
+func aspects(args []string) {
+	var k, minWords int
+	conf := parseArgs("aspects", args, func(fs *flag.FlagSet) {
+		fs.IntVar(&k, "k", 5, "Number of sentences per local topic")
+		fs.IntVar(&minWords, "min_words", 3, "Minimum number of words of a sentence")
+	})
+	check(conf.validate())
+
+	m, vocabulary := loadModel(conf.ModelPath)
+
+	out := createOutput(conf.OutPath)
+	defer checkClose(out)
+	wt := bufio.NewWriter(out)
+	defer wt.Flush()
+	sentences := m.RepresentativeSentences(k, minWords, vocabulary)
+	if conf.OutputFormat == "json" {
+		enc := json.NewEncoder(wt)
+		enc.SetIndent("", "  ")
+		check(enc.Encode(sentences))
+		return
+	}
+	mglda.WriteRepresentativeSentences(wt, sentences, m.Labels)
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bufio"
+
+	"github.com/yuui-ro/mglda"
+)
+
+// writeCoherence writes the coherence of every topic of m.
+func writeCoherence(conf *Configuration, m *mglda.MGLDA, wt *bufio.Writer) {
+	var reference []mglda.Document
+	if conf.ReferencePath != "" {
+		data := Data{}
+		check(data.parse(conf.ReferencePath))
+		reference = data.Docs
+	}
+	unit := mglda.DocumentUnit
+	if conf.CoherenceUnit == "sentence" {
+		unit = mglda.SentenceUnit
+	}
+	wt.WriteString("==== coherence ====\n")
+	mglda.WriteCoherence(wt, m.Coherence(conf.TopN, reference, unit))
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/yuui-ro/mglda"
+)
+
+// Configuration is shared by all subcommands. Every key can be set in the
+// configuration file and overridden by the flag of the same name.
+type Configuration struct {
+	GlobalK        int     `json:"global_k"`
+	LocalK         int     `json:"local_k"`
+	Gamma          float64 `json:"gamma"`
+	GlobalAlpha    float64 `json:"global_alpha"`
+	LocalAlpha     float64 `json:"local_alpha"`
+	GlobalAlphaMix float64 `json:"global_alpha_mix"`
+	LocalAlphaMix  float64 `json:"local_alpha_mix"`
+	GlobalBeta     float64 `json:"global_beta"`
+	LocalBeta      float64 `json:"local_beta"`
+	T              int     `json:"t"`
+	W              int     `json:"w"`
+	Iteration      int     `json:"iteration"`
+	TrainBurnin    int     `json:"train_burnin"`
+	TestBurnin     int     `json:"test_burnin"`
+	SampleSpace    int     `json:"sample_space"`
+	Seed           int64   `json:"seed"`
+	TopN           int     `json:"top_n"`
+	TopicWords     int     `json:"topic_words"`
+	RankBy         string  `json:"rank_by"`
+	Lambda         float64 `json:"relevance_lambda"`
+	FREXWeight     float64 `json:"frex_weight"`
+	CoherenceUnit  string  `json:"coherence_unit"`
+	Aspects        int     `json:"aspects"`
+	RatingLevels   int     `json:"rating_levels"`
+	RatingRate     float64 `json:"rating_rate"`
+	RatingL2       float64 `json:"rating_l2"`
+	ReferencePath  string  `json:"reference_path"`
+	SeedsPath      string  `json:"seeds_path"`
+	LabelsPath     string  `json:"labels_path"`
+	OOV            string  `json:"oov"`
+	Trainer        string  `json:"trainer"`
+	BatchSize      int     `json:"batch_size"`
+	Tau0           float64 `json:"tau0"`
+	Kappa          float64 `json:"kappa"`
+	LocalIteration int     `json:"local_iteration"`
+	Tolerance      float64 `json:"tolerance"`
+	HDP            bool    `json:"hdp"`
+	HDPAlpha       float64 `json:"hdp_alpha"`
+	HDPGamma       float64 `json:"hdp_gamma"`
+	WindowRatio    float64 `json:"window_ratio"`
+	LearnWindow    bool    `json:"learn_window"`
+	WindowPrior    float64 `json:"window_prior"`
+	WindowPosition float64 `json:"window_position"`
+	LearnGamma     bool    `json:"learn_gamma"`
+	Covariates     string  `json:"covariates"`
+	CovariateSigma float64 `json:"covariate_sigma"`
+	CovariateRate  float64 `json:"covariate_rate"`
+	Chains         int     `json:"chains"`
+	ChainBurnin    int     `json:"chain_burnin"`
+	DataPath       string  `json:"data_path"`
+	ModelPath      string  `json:"model_path"`
+	OutPath        string  `json:"out_path"`
+	OutputFormat   string  `json:"output_format"`
+	LoglikeFile    string  `json:"loglike_file"`
+	DocnumFile     string  `json:"docnum_file"`
+	PerplexityFile string  `json:"perplexity_file"`
+}
+
+func defaultConfiguration() *Configuration {
+	return &Configuration{
+		GlobalK:        60,
+		LocalK:         30,
+		Gamma:          0.1,
+		GlobalAlpha:    0.1,
+		LocalAlpha:     0.1,
+		GlobalAlphaMix: 0.1,
+		LocalAlphaMix:  0.1,
+		GlobalBeta:     0.1,
+		LocalBeta:      0.1,
+		T:              3,
+		Iteration:      1000,
+		TrainBurnin:    3000,
+		TestBurnin:     3000,
+		SampleSpace:    500,
+		TopN:           10,
+		TopicWords:     20,
+		RankBy:         string(mglda.ByProbability),
+		Lambda:         1,
+		FREXWeight:     0.5,
+		CoherenceUnit:  "document",
+		RatingLevels:   5,
+		RatingRate:     0.01,
+		RatingL2:       0.01,
+		OOV:            string(mglda.SkipOOV),
+		Trainer:        "gibbs",
+		BatchSize:      mglda.DefaultSVIOptions().BatchSize,
+		Tau0:           mglda.DefaultSVIOptions().Tau0,
+		Kappa:          mglda.DefaultSVIOptions().Kappa,
+		LocalIteration: mglda.DefaultSVIOptions().LocalIterations,
+		Tolerance:      1e-4,
+		HDPAlpha:       1,
+		HDPGamma:       1,
+		WindowPrior:    1,
+		WindowPosition: 1,
+		CovariateSigma: 1,
+		CovariateRate:  0.01,
+		Chains:         1,
+		ChainBurnin:    -1,
+		DataPath:       "data.json",
+		OutputFormat:   "text",
+	}
+}
+
+// parse reads a configuration file over d. Unknown keys are an error so
+// that a misspelled key cannot silently fall back to its default.
+func (d *Configuration) parse(fn string) error {
+	bt, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(bt))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(d); err != nil {
+		return fmt.Errorf("%s: %v", fn, err)
+	}
+	return nil
+}
+
+func (d *Configuration) validate() error {
+	switch {
+	case d.GlobalK <= 0 || d.LocalK <= 0:
+		return fmt.Errorf("global_k and local_k must be positive")
+	case d.T <= 0:
+		return fmt.Errorf("t must be positive")
+	case d.TopN <= 0:
+		return fmt.Errorf("top_n must be positive")
+	case d.TopicWords <= 0:
+		return fmt.Errorf("topic_words must be positive")
+	case d.RankBy != string(mglda.ByProbability) && d.RankBy != string(mglda.ByRelevance) &&
+		d.RankBy != string(mglda.ByLift) && d.RankBy != string(mglda.ByFREX):
+		return fmt.Errorf("rank_by must be prob, relevance, lift or frex")
+	case d.Lambda < 0 || d.Lambda > 1 || d.FREXWeight < 0 || d.FREXWeight > 1:
+		return fmt.Errorf("relevance_lambda and frex_weight must be in [0, 1]")
+	case d.OOV != string(mglda.SkipOOV) && d.OOV != string(mglda.UnknownOOV) && d.OOV != string(mglda.GrowOOV):
+		return fmt.Errorf("oov must be skip, unk or grow")
+	case d.Trainer != "gibbs" && d.Trainer != "svi" && d.Trainer != "cvb0":
+		return fmt.Errorf("trainer must be gibbs, svi or cvb0")
+	case d.Trainer != "gibbs" && (d.Aspects > 0 || d.SeedsPath != ""):
+		return fmt.Errorf("aspects and seeds_path need the gibbs trainer")
+	case d.HDP && d.Trainer != "gibbs":
+		return fmt.Errorf("hdp needs the gibbs trainer")
+	case d.HDP && (d.HDPAlpha <= 0 || d.HDPGamma <= 0):
+		return fmt.Errorf("hdp_alpha and hdp_gamma must be positive")
+	case d.WindowRatio < 0:
+		return fmt.Errorf("window_ratio must be non-negative")
+	case d.LearnWindow && d.Trainer != "gibbs":
+		return fmt.Errorf("learn_window needs the gibbs trainer")
+	case d.LearnWindow && d.WindowPrior <= 0:
+		return fmt.Errorf("window_prior must be positive")
+	case d.WindowPosition <= 0:
+		return fmt.Errorf("window_position must be positive")
+	case (d.WindowPosition != 1 || d.LearnGamma) && d.Trainer != "gibbs":
+		return fmt.Errorf("window_position and learn_gamma need the gibbs trainer")
+	case d.Covariates != "" && (d.Trainer != "gibbs" || d.HDP):
+		return fmt.Errorf("covariates need the gibbs trainer without hdp")
+	case d.Covariates != "" && (d.CovariateSigma <= 0 || d.CovariateRate <= 0):
+		return fmt.Errorf("covariate_sigma and covariate_rate must be positive")
+	case d.Chains <= 0:
+		return fmt.Errorf("chains must be positive")
+	case d.Chains > 1 && (d.Trainer != "gibbs" || d.HDP):
+		return fmt.Errorf("chains need the gibbs trainer without hdp")
+	case d.BatchSize <= 0 || d.LocalIteration <= 0 || d.Tau0 < 0 || d.Kappa <= 0.5 || d.Kappa > 1:
+		return fmt.Errorf("batch_size and local_iteration must be positive, tau0 non-negative and kappa in (0.5, 1]")
+	case !outputFormats[d.OutputFormat]:
+		return fmt.Errorf("output_format must be text, json, csv, tsv or npy")
+	case d.CoherenceUnit != "document" && d.CoherenceUnit != "sentence":
+		return fmt.Errorf("coherence_unit must be document or sentence")
+	case d.Gamma <= 0 || d.GlobalAlpha <= 0 || d.LocalAlpha <= 0 ||
+		d.GlobalAlphaMix <= 0 || d.LocalAlphaMix <= 0 ||
+		d.GlobalBeta <= 0 || d.LocalBeta <= 0:
+		return fmt.Errorf("hyperparameters must be positive")
+	case d.Aspects < 0 || d.Aspects > d.LocalK:
+		return fmt.Errorf("aspects must be in [0, local_k]")
+	case d.Aspects > 0 && (d.RatingLevels < 2 || d.RatingRate <= 0 || d.RatingL2 < 0):
+		return fmt.Errorf("rating_levels must be at least 2, rating_rate positive and rating_l2 non-negative")
+	}
+	return nil
+}
+
+// flags registers a flag for every configuration key on fs, bound to d.
+func (d *Configuration) flags(fs *flag.FlagSet) {
+	fs.IntVar(&d.GlobalK, "global_k", d.GlobalK, "Number of global topics")
+	fs.IntVar(&d.LocalK, "local_k", d.LocalK, "Number of local topics")
+	fs.Float64Var(&d.Gamma, "gamma", d.Gamma, "Prior of the window distribution of a sentence")
+	fs.Float64Var(&d.GlobalAlpha, "global_alpha", d.GlobalAlpha, "Prior of the global topic distribution")
+	fs.Float64Var(&d.LocalAlpha, "local_alpha", d.LocalAlpha, "Prior of the local topic distribution")
+	fs.Float64Var(&d.GlobalAlphaMix, "global_alpha_mix", d.GlobalAlphaMix, "Prior weight of global topics in a window")
+	fs.Float64Var(&d.LocalAlphaMix, "local_alpha_mix", d.LocalAlphaMix, "Prior weight of local topics in a window")
+	fs.Float64Var(&d.GlobalBeta, "global_beta", d.GlobalBeta, "Prior of the global topic word distribution")
+	fs.Float64Var(&d.LocalBeta, "local_beta", d.LocalBeta, "Prior of the local topic word distribution")
+	fs.IntVar(&d.T, "t", d.T, "Number of sentences in a window (the largest window size with window_ratio or learn_window)")
+	fs.Float64Var(&d.WindowRatio, "window_ratio", d.WindowRatio, "Window size of a document without one as this fraction of its sentences, up to t (0 uses t)")
+	fs.BoolVar(&d.LearnWindow, "learn_window", d.LearnWindow, "Learn the window size of every document and a prior over the sizes 1..t")
+	fs.Float64Var(&d.WindowPrior, "window_prior", d.WindowPrior, "Dirichlet parameter of the learned prior over window sizes")
+	fs.Float64Var(&d.WindowPosition, "window_position", d.WindowPosition, "Ratio of the prior weights of consecutive windows of a sentence (above 1 favours the window starting at the sentence)")
+	fs.BoolVar(&d.LearnGamma, "learn_gamma", d.LearnGamma, "Fit the prior weight of every window of a sentence after every sweep")
+	fs.IntVar(&d.W, "w", d.W, "Vocabulary size (defaults to the size of the vocabulary in the data file)")
+	fs.IntVar(&d.Iteration, "iteration", d.Iteration, "Number of Gibbs sweeps")
+	fs.IntVar(&d.TrainBurnin, "train_burnin", d.TrainBurnin, "Number of burnin iterations for training data")
+	fs.IntVar(&d.TestBurnin, "test_burnin", d.TestBurnin, "Number of burnin iterations for each test document")
+	fs.IntVar(&d.SampleSpace, "sample_space", d.SampleSpace, "Number of iterations for evaluating the harmonic mean for each holdout document")
+	fs.Int64Var(&d.Seed, "seed", d.Seed, "Random seed (0 uses the default source)")
+	fs.IntVar(&d.TopN, "top_n", d.TopN, "Number of top words per topic for coherence and diagnostics")
+	fs.IntVar(&d.TopicWords, "topic_words", d.TopicWords, "Number of words listed per topic")
+	fs.StringVar(&d.RankBy, "rank_by", d.RankBy, "Ranking of listed words: prob, relevance, lift or frex")
+	fs.Float64Var(&d.Lambda, "relevance_lambda", d.Lambda, "Weight of probability against lift in relevance ranking")
+	fs.Float64Var(&d.FREXWeight, "frex_weight", d.FREXWeight, "Weight of exclusivity against frequency in FREX ranking")
+	fs.StringVar(&d.CoherenceUnit, "coherence_unit", d.CoherenceUnit, "Co-occurrence context for coherence: document or sentence")
+	fs.IntVar(&d.Aspects, "aspects", d.Aspects, "Number of rated aspects tied to the first local topics (0 disables ratings)")
+	fs.IntVar(&d.RatingLevels, "rating_levels", d.RatingLevels, "Number of rating levels of an aspect")
+	fs.Float64Var(&d.RatingRate, "rating_rate", d.RatingRate, "Learning rate of the rating predictor")
+	fs.Float64Var(&d.RatingL2, "rating_l2", d.RatingL2, "L2 penalty of the rating predictor")
+	fs.StringVar(&d.Trainer, "trainer", d.Trainer, "Training algorithm: gibbs, svi for stochastic variational inference (iteration counts epochs) or cvb0")
+	fs.IntVar(&d.BatchSize, "batch_size", d.BatchSize, "Documents per mini-batch of svi")
+	fs.Float64Var(&d.Tau0, "tau0", d.Tau0, "Delay of the svi learning rate (tau0 + t)^-kappa")
+	fs.Float64Var(&d.Kappa, "kappa", d.Kappa, "Forgetting rate of the svi learning rate, in (0.5, 1]")
+	fs.IntVar(&d.LocalIteration, "local_iteration", d.LocalIteration, "Rounds of the per-document updates of svi")
+	fs.Float64Var(&d.Tolerance, "tolerance", d.Tolerance, "cvb0 stops when no responsibility changes by more")
+	fs.BoolVar(&d.HDP, "hdp", d.HDP, "Let the number of global topics grow and shrink from global_k by an HDP")
+	fs.Float64Var(&d.HDPAlpha, "hdp_alpha", d.HDPAlpha, "Concentration of the global topic distribution of a document under hdp")
+	fs.Float64Var(&d.HDPGamma, "hdp_gamma", d.HDPGamma, "Concentration of the top-level global topic weights under hdp")
+	fs.StringVar(&d.Covariates, "covariates", d.Covariates, "Comma separated metadata fields the global topic prior depends on")
+	fs.Float64Var(&d.CovariateSigma, "covariate_sigma", d.CovariateSigma, "Standard deviation of the prior of the covariate coefficients")
+	fs.Float64Var(&d.CovariateRate, "covariate_rate", d.CovariateRate, "Learning rate of the covariate coefficients")
+	fs.IntVar(&d.Chains, "chains", d.Chains, "Number of independent chains trained in parallel, with convergence diagnostics if more than 1")
+	fs.IntVar(&d.ChainBurnin, "chain_burnin", d.ChainBurnin, "Sweeps left out of the convergence diagnostics (negative uses half of iteration)")
+	fs.StringVar(&d.ReferencePath, "reference_path", d.ReferencePath, "Reference corpus in json for NPMI and C_V coherence (training data if empty)")
+	fs.StringVar(&d.SeedsPath, "seeds_path", d.SeedsPath, "Seed word file in json anchoring topics to named aspects")
+	fs.StringVar(&d.LabelsPath, "labels_path", d.LabelsPath, "Topic label file in json")
+	fs.StringVar(&d.OOV, "oov", d.OOV, "Handling of words missing from the model vocabulary: skip, unk or grow")
+	fs.StringVar(&d.DataPath, "data_path", d.DataPath, "Data file in json")
+	fs.StringVar(&d.ModelPath, "model_path", d.ModelPath, "Model file")
+	fs.StringVar(&d.OutPath, "out_path", d.OutPath, "Output file (standard output if empty)")
+	fs.StringVar(&d.OutputFormat, "output_format", d.OutputFormat, "Format of the topic output: text, json, csv, tsv or npy (out_path is the file name prefix)")
+	fs.StringVar(&d.LoglikeFile, "loglike_file", d.LoglikeFile, "Output file for loglikelihood of holdout documents")
+	fs.StringVar(&d.DocnumFile, "docnum_file", d.DocnumFile, "Output file for number of words of holdout documents")
+	fs.StringVar(&d.PerplexityFile, "perplexity_file", d.PerplexityFile, "Output file for perplexity of holdout documents")
+}
+
+// parseArgs parses the arguments of a subcommand. Defaults are overridden by
+// the configuration file given with -c, which is in turn overridden by
+// flags. extra registers flags specific to the subcommand.
+func parseArgs(name string, args []string, extra func(fs *flag.FlagSet)) *Configuration {
+	newFlagSet := func(d *Configuration, confFile *string) *flag.FlagSet {
+		fs := flag.NewFlagSet(name, flag.ExitOnError)
+		fs.StringVar(confFile, "c", "", "Configuration file")
+		d.flags(fs)
+		if extra != nil {
+			extra(fs)
+		}
+		return fs
+	}
+
+	// the first pass only finds the configuration file
+	var confFile string
+	newFlagSet(defaultConfiguration(), &confFile).Parse(args)
+
+	conf := defaultConfiguration()
+	if confFile != "" {
+		check(conf.parse(confFile))
+	}
+	newFlagSet(conf, &confFile).Parse(args)
+	return conf
+}
+
+type Data struct {
+	Docs       []mglda.Document `json:"docs"`
+	Vocabulary []string         `json:"vocabulary"`
+}
+
+func (d *Data) parse(fn string) error {
+	bt, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(bt, d)
+	return err
+}
+
+func (d *Data) write(fn string) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fn, b, 0644)
+}
+
+// encode maps the documents of d onto the vocabulary of m. Documents with
+// their own vocabulary are mapped word by word, handling words missing from
+// vocabulary by policy, which may grow m; otherwise their ids must be ids
+// of the model. It returns the documents and the vocabulary of m.
+func (d *Data) encode(m *mglda.MGLDA, vocabulary []string, policy mglda.OOVPolicy) ([]mglda.Document, []string) {
+	if len(d.Vocabulary) == 0 {
+		check(mglda.ValidateDocuments(d.Docs, m.W))
+		return d.Docs, vocabulary
+	}
+	check(mglda.ValidateDocuments(d.Docs, len(d.Vocabulary)))
+	texts := make([][][]string, len(d.Docs))
+	for i, doc := range d.Docs {
+		for _, sent := range doc.Sentenses {
+			words := make([]string, len(sent.Words))
+			for j, wd := range sent.Words {
+				words[j] = d.Vocabulary[wd]
+			}
+			texts[i] = append(texts[i], words)
+		}
+	}
+	v := mglda.NewVocabulary(vocabulary)
+	docs := m.Encode(v, texts, policy)
+	for i := range docs {
+		docs[i].Ratings = d.Docs[i].Ratings
+		docs[i].Window = d.Docs[i].Window
+		docs[i].Time = d.Docs[i].Time
+		docs[i].Meta = d.Docs[i].Meta
+	}
+	return docs, v.Words
+}
+
+// windows sets the window size of the documents of docs without one by
+// window_ratio and verifies that no window is larger than t.
+func (d *Configuration) windows(docs []mglda.Document, t int) {
+	if d.WindowRatio > 0 {
+		mglda.SetWindows(docs, mglda.ProportionalWindow(d.WindowRatio, t))
+	}
+	check(mglda.ValidateWindows(docs, t))
+}
+
+func (d *Configuration) sviOptions() mglda.SVIOptions {
+	return mglda.SVIOptions{
+		BatchSize:       d.BatchSize,
+		Tau0:            d.Tau0,
+		Kappa:           d.Kappa,
+		LocalIterations: d.LocalIteration,
+	}
+}
+
+func (d *Configuration) topWordsOptions() mglda.TopWordsOptions {
+	return mglda.TopWordsOptions{
+		N:          d.TopicWords,
+		Rank:       mglda.RankBy(d.RankBy),
+		Lambda:     d.Lambda,
+		FREXWeight: d.FREXWeight,
+	}
+}
+
+// vocabularySize returns the configured vocabulary size, or the size of the
+// vocabulary of data if it is not configured.
+func (d *Configuration) vocabularySize(data *Data) int {
+	if d.W > 0 {
+		return d.W
+	}
+	return len(data.Vocabulary)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yuui-ro/mglda"
+)
+
+type convertOptions struct {
+	format        string
+	corpus        string
+	vocab         string
+	sentenceSize  int
+	markSentences bool
+	tokens        bool
+	trainSize     int
+}
+
+func (o *convertOptions) flags(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", "lines", "Corpus format: lines, uci, ldac or mallet")
+	fs.StringVar(&o.corpus, "corpus", "corpus", "Corpus file (docword file for uci)")
+	fs.StringVar(&o.vocab, "vocab", "", "Vocabulary file, one word per line or id<TAB>word")
+	fs.IntVar(&o.sentenceSize, "sentence_size", 0, "Number of words per sentence for bag-of-words input (0 keeps each document as one sentence)")
+	fs.BoolVar(&o.markSentences, "mark_sentences", false, "Write the sentence-marked variant of uci and ldac")
+	fs.BoolVar(&o.tokens, "tokens", false, "lines format contains words instead of word ids")
+	fs.IntVar(&o.trainSize, "train_size", -1, "Number of documents for training, the rest are holdout documents (all if negative)")
+}
+
+// convert imports a corpus into the json data file at data_path, or exports
+// the json data file into a corpus.
+func convert(args []string) {
+	if len(args) < 1 || (args[0] != "import" && args[0] != "export") {
+		fmt.Fprintf(os.Stderr, "usage: %s convert import|export [flags]\n", os.Args[0])
+		os.Exit(2)
+	}
+	o := &convertOptions{}
+	conf := parseArgs("convert "+args[0], args[1:], o.flags)
+	if args[0] == "import" {
+		importCorpus(conf, o)
+	} else {
+		exportCorpus(conf, o)
+	}
+}
+
+func importCorpus(conf *Configuration, o *convertOptions) {
+	in, err := os.Open(o.corpus)
+	check(err)
+	defer in.Close()
+
+	var vocab io.Reader
+	var vocabulary []string
+	if o.vocab != "" {
+		fp, err := os.Open(o.vocab)
+		check(err)
+		defer fp.Close()
+		vocab = fp
+	}
+
+	data := &Data{}
+	switch o.format {
+	case "lines":
+		if vocab != nil {
+			vocabulary, err = mglda.ReadVocabulary(vocab)
+			check(err)
+		} else if !o.tokens {
+			check(fmt.Errorf("either -vocab or -tokens is required"))
+		}
+		data.Docs, data.Vocabulary, err = mglda.ReadLines(in, vocabulary, o.tokens)
+	case "uci":
+		data.Docs, data.Vocabulary, err = mglda.ReadUCI(in, vocab, o.sentenceSize)
+	case "ldac":
+		data.Docs, data.Vocabulary, err = mglda.ReadLDAC(in, vocab, o.sentenceSize)
+	case "mallet":
+		data.Docs, data.Vocabulary, err = mglda.ReadMallet(in)
+	default:
+		err = fmt.Errorf("unknown format %q", o.format)
+	}
+	check(err)
+	check(mglda.ValidateDocuments(data.Docs, len(data.Vocabulary)))
+
+	for d := range data.Docs {
+		if o.trainSize < 0 || d < o.trainSize {
+			data.Docs[d].State = mglda.Active
+		} else {
+			data.Docs[d].State = mglda.Holdout
+		}
+	}
+
+	fmt.Printf("Read %d documents, %d words.\n", len(data.Docs), len(data.Vocabulary))
+	check(data.write(conf.DataPath))
+}
+
+func exportCorpus(conf *Configuration, o *convertOptions) {
+	data := &Data{}
+	check(data.parse(conf.DataPath))
+
+	out, err := os.Create(o.corpus)
+	check(err)
+	defer checkClose(out)
+
+	var vocab io.Writer
+	if o.vocab != "" {
+		fp, err := os.Create(o.vocab)
+		check(err)
+		defer fp.Close()
+		vocab = fp
+	}
+
+	switch o.format {
+	case "lines":
+		err = mglda.WriteLines(out, data.Docs, data.Vocabulary, o.tokens)
+		if err == nil && vocab != nil {
+			err = mglda.WriteVocabulary(vocab, data.Vocabulary)
+		}
+	case "uci":
+		err = mglda.WriteUCI(out, vocab, data.Docs, data.Vocabulary, o.markSentences)
+	case "ldac":
+		err = mglda.WriteLDAC(out, vocab, data.Docs, data.Vocabulary, o.markSentences)
+	case "mallet":
+		err = mglda.WriteMallet(out, data.Docs, data.Vocabulary)
+	default:
+		err = fmt.Errorf("unknown format %q", o.format)
+	}
+	check(err)
+}
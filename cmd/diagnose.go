@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+
+	"github.com/yuui-ro/mglda"
+)
+
+// diagnose reports topic diversity, near-duplicate topics and junk topics of
+// the model in model_path.
+func diagnose(args []string) {
+	opt := mglda.DefaultDiagnosticsOptions()
+	conf := parseArgs("diagnose", args, func(fs *flag.FlagSet) {
+		fs.Float64Var(&opt.DuplicateJS, "duplicate_js", opt.DuplicateJS, "Jensen-Shannon divergence below which two topics are duplicates")
+		fs.Float64Var(&opt.UniformEntropy, "uniform_entropy", opt.UniformEntropy, "Relative entropy above which a topic is too uniform")
+		fs.Float64Var(&opt.MinTokens, "min_tokens", opt.MinTokens, "Number of tokens below which a topic is junk")
+	})
+	check(conf.validate())
+	opt.TopN = conf.TopN
+
+	m, _ := loadModel(conf.ModelPath)
+
+	out := createOutput(conf.OutPath)
+	defer checkClose(out)
+	wt := bufio.NewWriter(out)
+	defer wt.Flush()
+	mglda.WriteDiagnostics(wt, m.Diagnostics(opt))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yuui-ro/mglda"
+)
+
+// edit merges, deletes or splits a topic of the model in model_path, runs
+// refine sweeps of training, saves the model in place and writes its
+// topics.
+func edit(args []string) {
+	var merge, del, split string
+	var refine int
+	conf := parseArgs("edit", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&merge, "merge", "", "Merge topic b into topic a, given as kind:a:b")
+		fs.StringVar(&del, "delete", "", "Delete a topic, given as kind:topic")
+		fs.StringVar(&split, "split", "", "Split a topic in two, given as kind:topic")
+		fs.IntVar(&refine, "refine", 0, "Number of refinement sweeps after the edit")
+	})
+	check(conf.validate())
+	ops := 0
+	for _, op := range []string{merge, del, split} {
+		if op != "" {
+			ops++
+		}
+	}
+	if ops != 1 {
+		check(fmt.Errorf("edit: exactly one of -merge, -delete and -split is required"))
+	}
+
+	m, vocabulary := loadModel(conf.ModelPath)
+	seed(m, conf.Seed)
+	switch {
+	case merge != "":
+		kind, ids := parseTopics(merge, 2)
+		check(m.MergeTopics(kind, ids[0], ids[1]))
+	case del != "":
+		kind, ids := parseTopics(del, 1)
+		check(m.DeleteTopic(kind, ids[0]))
+	default:
+		kind, ids := parseTopics(split, 1)
+		_, err := m.SplitTopic(kind, ids[0])
+		check(err)
+	}
+	m.Train(refine)
+	saveModel(conf.ModelPath, m, vocabulary)
+
+	wt, closeOutput := openOutput(conf)
+	defer closeOutput()
+	if conf.OutputFormat != "text" {
+		writeStructured(conf, m, vocabulary, wt)
+		return
+	}
+	mglda.WriteTopics(m, vocabulary, conf.topWordsOptions(), wt)
+}
+
+// parseTopics parses kind:id[:id...] with n topic ids.
+func parseTopics(spec string, n int) (mglda.TopicKind, []int) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != n+1 {
+		check(fmt.Errorf("edit: %q: want kind and %d topic ids separated by ':'", spec, n))
+	}
+	ids := make([]int, n)
+	for i := range ids {
+		id, err := strconv.Atoi(parts[i+1])
+		check(err)
+		ids[i] = id
+	}
+	return mglda.TopicKind(parts[0]), ids
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"github.com/yuui-ro/mglda"
+)
+
+func sumOfArrayFloat64(array *[]float64) float64 {
+	sum := 0.0
+	for _, e := range *array {
+		sum += e
+	}
+	return sum
+}
+
+func sumOfArrayInt(array *[]int) int {
+	sum := 0
+	for _, e := range *array {
+		sum += e
+	}
+	return sum
+}
+
+// writeResult writes s to fn unless fn is empty.
+func writeResult(fn, s string) {
+	if fn != "" {
+		check(ioutil.WriteFile(fn, []byte(s), 0644))
+	}
+}
+
+func eval(args []string) {
+	conf := parseArgs("eval", args, nil)
+	check(conf.validate())
+
+	fmt.Printf("train burnin: %d,\ntest burnin: %d, sample space: %d\n",
+		conf.TrainBurnin, conf.TestBurnin, conf.SampleSpace)
+
+	data := Data{}
+	check(data.parse(conf.DataPath))
+	uW := conf.vocabularySize(&data)
+	docs := data.Docs
+	check(mglda.ValidateDocuments(docs, uW))
+	conf.windows(docs, conf.T)
+
+	m := newModel(conf, uW, &docs)
+
+	wt := bufio.NewWriter(os.Stdout)
+	defer wt.Flush()
+
+	_, dochmloglik, numWords := mglda.EvaluateHoldout(m, conf.TrainBurnin,
+		conf.TestBurnin, conf.SampleSpace, wt)
+
+	holdoutLoglik := sumOfArrayFloat64(&dochmloglik)
+	holdoutNumWords := sumOfArrayInt(&numWords)
+	holdoutPerplexity := math.Exp(-1.0 * holdoutLoglik / float64(holdoutNumWords))
+
+	fmt.Fprintf(wt, "number of words: %d.\n", holdoutNumWords)
+	fmt.Fprintf(wt, "loglikelihood: %f.\n", holdoutLoglik)
+	fmt.Fprintf(wt, "perplexity: %f.\n", holdoutPerplexity)
+	writeResult(conf.DocnumFile, fmt.Sprint(holdoutNumWords))
+	writeResult(conf.LoglikeFile, fmt.Sprintf("%f", holdoutLoglik))
+	writeResult(conf.PerplexityFile, fmt.Sprintf("%f", holdoutPerplexity))
+}
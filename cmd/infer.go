@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+
+	"github.com/yuui-ro/mglda"
+)
+
+type docTopics struct {
+	Doc    int                    `json:"doc"`
+	Meta   map[string]interface{} `json:"meta,omitempty"`
+	Global []float64              `json:"global"`
+	Local  []float64              `json:"local"`
+}
+
+// infer samples the documents of data_path against a trained model and
+// writes one json object with the topic distributions per document.
+func infer(args []string) {
+	conf := parseArgs("infer", args, nil)
+	check(conf.validate())
+
+	m, vocabulary := loadModel(conf.ModelPath)
+	seed(m, conf.Seed)
+	data := Data{}
+	check(data.parse(conf.DataPath))
+	docs, _ := data.encode(m, vocabulary, mglda.OOVPolicy(conf.OOV))
+	conf.windows(docs, m.T)
+
+	first := m.Infer(docs, conf.Iteration)
+
+	out := createOutput(conf.OutPath)
+	defer checkClose(out)
+	wt := bufio.NewWriter(out)
+	defer wt.Flush()
+	enc := json.NewEncoder(wt)
+	for d := range docs {
+		gl, loc := m.DocTopicDist(first + d)
+		check(enc.Encode(docTopics{Doc: d, Meta: docs[d].Meta, Global: gl, Local: loc}))
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/yuui-ro/mglda"
+)
+
+func inspect(args []string) {
+	conf := parseArgs("inspect", args, nil)
+	check(conf.validate())
+
+	m, vocabulary := loadModel(conf.ModelPath)
+
+	wt, closeOutput := openOutput(conf)
+	defer closeOutput()
+	if conf.OutputFormat != "text" {
+		writeStructured(conf, m, vocabulary, wt)
+		return
+	}
+
+	states := map[mglda.DocumentState]int{}
+	words := 0
+	for _, doc := range *m.Docs {
+		states[doc.State]++
+		words += doc.NumberOfWords()
+	}
+	fmt.Fprintf(wt, "global_k: %d\nlocal_k: %d\nt: %d\nw: %d\n", m.GlobalK, m.LocalK, m.T, m.W)
+	fmt.Fprintf(wt, "gamma: %g\nglobal_alpha: %g\nlocal_alpha: %g\n", m.Gamma, m.GlobalAlpha, m.LocalAlpha)
+	fmt.Fprintf(wt, "global_alpha_mix: %g\nlocal_alpha_mix: %g\n", m.GlobalAlphaMix, m.LocalAlphaMix)
+	fmt.Fprintf(wt, "global_beta: %g\nlocal_beta: %g\n", m.GlobalBeta, m.LocalBeta)
+	fmt.Fprintf(wt, "documents: %d (active %d, frozen %d, holdout %d)\nwords: %d\n",
+		len(*m.Docs), states[mglda.Active], states[mglda.Frozen], states[mglda.Holdout], words)
+	fmt.Fprintf(wt, "loglikelihood: %f\n", m.LogLikelihood())
+	mglda.WriteTopics(m, vocabulary, conf.topWordsOptions(), wt)
+	writeCoherence(conf, m, wt)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yuui-ro/mglda"
+)
+
+// label attaches the topic labels of labels_path to the model in model_path,
+// optionally suggests labels for the remaining topics from their top words,
+// saves the model and writes its labels.
+func label(args []string) {
+	var suggest bool
+	var nameWords int
+	conf := parseArgs("label", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&suggest, "suggest", false, "Suggest labels from the top words of unlabelled topics")
+		fs.IntVar(&nameWords, "name_words", 3, "Number of top words in a suggested name")
+	})
+	check(conf.validate())
+	if conf.ModelPath == "" {
+		check(fmt.Errorf("label: model_path is required"))
+	}
+
+	m, vocabulary := loadModel(conf.ModelPath)
+	labels := m.Labels
+	if conf.LabelsPath != "" {
+		labels = readLabels(conf.LabelsPath).Merge(labels)
+	}
+	if suggest {
+		labels = labels.Merge(m.SuggestLabels(vocabulary, nameWords, conf.topWordsOptions()))
+	}
+	check(m.SetLabels(labels))
+	saveModel(conf.ModelPath, m, vocabulary)
+
+	out := createOutput(conf.OutPath)
+	defer checkClose(out)
+	wt := bufio.NewWriter(out)
+	defer wt.Flush()
+	check(mglda.WriteTopicLabels(wt, m.Labels))
+}
+
+func readLabels(fn string) mglda.TopicLabels {
+	fp, err := os.Open(fn)
+	check(err)
+	defer fp.Close()
+	labels, err := mglda.ReadTopicLabels(fp)
+	check(err)
+	return labels
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yuui-ro/mglda"
+)
+
+// ldavis exports the model in model_path in the JSON format of LDAvis, and
+// the topic labels to names_path if given.
+func ldavis(args []string) {
+	var topics, names string
+	conf := parseArgs("ldavis", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&topics, "topics", "both", "Topics to export: global, local or both")
+		fs.StringVar(&names, "names_path", "", "File to write the topic labels to, one per line")
+	})
+	check(conf.validate())
+
+	var kinds []mglda.TopicKind
+	switch topics {
+	case "global":
+		kinds = []mglda.TopicKind{mglda.Global}
+	case "local":
+		kinds = []mglda.TopicKind{mglda.Local}
+	case "both":
+		kinds = []mglda.TopicKind{mglda.Global, mglda.Local}
+	default:
+		check(fmt.Errorf("topics must be global, local or both"))
+	}
+
+	m, vocabulary := loadModel(conf.ModelPath)
+	data := m.LDAvis(vocabulary, kinds...)
+
+	if names != "" {
+		fp, err := os.Create(names)
+		check(err)
+		defer checkClose(fp)
+		wt := bufio.NewWriter(fp)
+		check(mglda.WriteLDAvisLabels(wt, data))
+		check(wt.Flush())
+	}
+
+	out := createOutput(conf.OutPath)
+	defer checkClose(out)
+	wt := bufio.NewWriter(out)
+	defer wt.Flush()
+	check(mglda.WriteLDAvis(wt, data))
+}
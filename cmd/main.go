@@ -2,78 +2,130 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"flag"
-	"io/ioutil"
+	"fmt"
+	"io"
 	"os"
+	"sort"
 
-	"gitlab.com/wujiang/mglda"
+	"github.com/yuui-ro/mglda"
 )
 
-type Configuration struct {
-	GlobalK        int     `json:"global_k"`
-	LocalK         int     `json:"local_k"`
-	Gamma          float64 `json:"gamma"`
-	GlobalAlpha    float64 `json:"global_alpha"`
-	LocalAlpha     float64 `json:"local_alpha"`
-	GlobalAlphaMix float64 `json:"global_alpha_mix"`
-	LocalAlphaMix  float64 `jons:"local_alpha_mix"`
-	GlobalBeta     float64 `json:"global_beta"`
-	LocalBeta      float64 `json:"local_beta"`
-	T              int     `json:"t"`
-	Interation     int     `josn:"interation"`
-	DataPath       string  `json:"data_path"`
-	OutPath        string  `json:"out_path"`
+type command struct {
+	run   func(args []string)
+	usage string
 }
 
-func (d *Configuration) parse(fn string) error {
-	bt, err := ioutil.ReadFile(fn)
-	if err != nil {
-		return err
-	}
-	err = json.Unmarshal(bt, d)
-	return err
+var commands = map[string]command{
+	"train":     {train, "train a model on data_path and write it to model_path"},
+	"eval":      {eval, "evaluate the perplexity of the holdout documents in data_path"},
+	"infer":     {infer, "infer topic distributions of data_path with the model in model_path"},
+	"convert":   {convert, "import a corpus to or export it from the json data format"},
+	"inspect":   {inspect, "print the hyperparameters and topics of the model in model_path"},
+	"diagnose":  {diagnose, "report duplicate and junk topics of the model in model_path"},
+	"ldavis":    {ldavis, "export the model in model_path for LDAvis"},
+	"report":    {report, "write an HTML report of the model in model_path"},
+	"aspects":   {aspects, "list the most representative sentences of every local topic"},
+	"update":    {update, "add the documents of data_path to the model in model_path and train them"},
+	"label":     {label, "attach, suggest and list the topic labels of the model in model_path"},
+	"predict":   {predict, "predict the aspect ratings of the reviews in data_path"},
+	"trends":    {trends, "write the prevalence of every topic per time bucket as csv"},
+	"aggregate": {aggregate, "write the mean topic distributions per value of a metadata field"},
+	"align":     {align, "match the topics of other models to the model in model_path"},
+	"edit":      {edit, "merge, delete or split a topic of the model in model_path"},
 }
 
-type Data struct {
-	Docs       []mglda.Document `json:"docs"`
-	Vocabulary []string         `json:"vocabulary"`
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [-c conf.json] [flags]\n\ncommands:\n", os.Args[0])
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-9s %s\n", name, commands[name].usage)
+	}
+	os.Exit(2)
 }
 
-func (d *Data) parse(fn string) error {
-	bt, err := ioutil.ReadFile(fn)
-	if err != nil {
-		return err
+func check(e error) {
+	if e != nil {
+		panic(e)
 	}
-	err = json.Unmarshal(bt, d)
-	return err
 }
 
-var (
-	confFile = flag.String("c", "conf.json", "Configuration file")
-)
-
-func main() {
-	flag.Parse()
-	conf := &Configuration{}
-	if err := conf.parse(*confFile); err != nil {
-		panic(err)
+// seed gives m a random source of its own seeded by seed unless seed is
+// zero.
+func seed(m *mglda.MGLDA, seed int64) {
+	if seed != 0 {
+		m.SetSeed(seed)
 	}
+}
 
-	data := Data{}
-	if err := data.parse(conf.DataPath); err != nil {
-		panic(err)
-	}
-	uW := len(data.Vocabulary)
-	docs := data.Docs
+// newModel returns a model of docs with the hyperparameters of conf and its
+// words assigned at random from the source seeded by conf.Seed.
+func newModel(conf *Configuration, uW int, docs *[]mglda.Document) *mglda.MGLDA {
 	m := mglda.NewMGLDA(conf.GlobalK, conf.LocalK, conf.Gamma,
 		conf.GlobalAlpha, conf.LocalAlpha,
 		conf.GlobalAlphaMix, conf.LocalAlphaMix,
 		conf.GlobalBeta, conf.LocalBeta,
-		conf.T, uW, &docs)
-	out, _ := os.Create(conf.OutPath)
-	defer out.Close()
-	wt := bufio.NewWriter(out)
-	defer wt.Flush()
-	mglda.Learning(m, conf.Interation, data.Vocabulary, wt)
+		conf.T, uW, docs)
+	if conf.Seed != 0 {
+		m.SetSeed(conf.Seed)
+		m.Randomize()
+	}
+	return m
+}
+
+// stdout is standard output with a Close that leaves it open.
+type stdout struct{ io.Writer }
+
+func (stdout) Close() error { return nil }
+
+// createOutput creates fn, or returns standard output if fn is empty. The
+// caller closes it with checkClose.
+func createOutput(fn string) io.WriteCloser {
+	if fn == "" {
+		return stdout{os.Stdout}
+	}
+	out, err := os.Create(fn)
+	check(err)
+	return out
+}
+
+// checkClose closes out and panics if that fails, which may mean the output
+// was not written completely.
+func checkClose(out io.Closer) {
+	check(out.Close())
+}
+
+func loadModel(fn string) (*mglda.MGLDA, []string) {
+	fp, err := os.Open(fn)
+	check(err)
+	defer fp.Close()
+	m, vocabulary, err := mglda.LoadModel(fp)
+	check(err)
+	return m, vocabulary
+}
+
+func saveModel(fn string, m *mglda.MGLDA, vocabulary []string) {
+	fp, err := os.Create(fn)
+	check(err)
+	defer fp.Close()
+	wt := bufio.NewWriter(fp)
+	check(mglda.SaveModel(wt, m, vocabulary))
+	check(wt.Flush())
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		usage()
+	}
+	cmd.run(flag.Args()[1:])
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"os"
+
+	"github.com/yuui-ro/mglda"
+)
+
+var outputFormats = map[string]bool{"text": true, "json": true, "csv": true, "tsv": true, "npy": true}
+
+// openOutput opens out_path for the topic output and returns a writer and a
+// function that flushes and closes it. The npy format writes its own files,
+// so the writer is nil.
+func openOutput(conf *Configuration) (*bufio.Writer, func()) {
+	if conf.OutputFormat == "npy" {
+		return nil, func() {}
+	}
+	out := createOutput(conf.OutPath)
+	wt := bufio.NewWriter(out)
+	return wt, func() {
+		check(wt.Flush())
+		checkClose(out)
+	}
+}
+
+// writeNpy writes rows to prefix+name+".npy".
+func writeNpy(prefix, name string, rows [][]float64) {
+	fp, err := os.Create(prefix + name + ".npy")
+	check(err)
+	defer fp.Close()
+	wt := bufio.NewWriter(fp)
+	check(mglda.WriteNpy(wt, rows))
+	check(wt.Flush())
+}
+
+// writeStructured writes the topics of m in the structured output format
+// conf.OutputFormat. The npy format writes the phi and theta matrices to
+// files whose names start with out_path instead of writing to wt.
+func writeStructured(conf *Configuration, m *mglda.MGLDA, vocabulary []string, wt *bufio.Writer) {
+	if conf.OutputFormat == "npy" {
+		phiGl, phiLoc := m.WordDist()
+		thetaGl, thetaLoc := m.Theta()
+		writeNpy(conf.OutPath, "phi_global", phiGl.Arrays())
+		writeNpy(conf.OutPath, "phi_local", phiLoc.Arrays())
+		writeNpy(conf.OutPath, "theta_global", thetaGl)
+		writeNpy(conf.OutPath, "theta_local", thetaLoc)
+		return
+	}
+
+	topics := m.Topics(vocabulary, conf.topWordsOptions())
+	switch conf.OutputFormat {
+	case "json":
+		check(mglda.WriteTopicsJSON(wt, topics))
+	case "csv":
+		check(mglda.WriteTopicsCSV(wt, topics, ','))
+	case "tsv":
+		check(mglda.WriteTopicsCSV(wt, topics, '\t'))
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+
+	"github.com/yuui-ro/mglda"
+)
+
+type docRatings struct {
+	Doc     int                    `json:"doc"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+	Aspects []string               `json:"aspects,omitempty"`
+	Ratings []float64              `json:"ratings"`
+}
+
+// predict infers the reviews of data_path against a model trained with
+// aspects and writes one json object with the expected aspect ratings per
+// review.
+func predict(args []string) {
+	conf := parseArgs("predict", args, nil)
+	check(conf.validate())
+
+	m, vocabulary := loadModel(conf.ModelPath)
+	seed(m, conf.Seed)
+	if m.Ratings == nil {
+		check(fmt.Errorf("%s: model was trained without aspects", conf.ModelPath))
+	}
+	data := Data{}
+	check(data.parse(conf.DataPath))
+	docs, _ := data.encode(m, vocabulary, mglda.OOVPolicy(conf.OOV))
+	conf.windows(docs, m.T)
+
+	out := createOutput(conf.OutPath)
+	defer checkClose(out)
+	wt := bufio.NewWriter(out)
+	defer wt.Flush()
+	var aspects []string
+	for a := 0; a < m.Ratings.Aspects && len(m.Labels) > 0; a++ {
+		aspects = append(aspects, m.Labels.Name(mglda.Local, a))
+	}
+	predicted, err := m.PredictRatings(docs, conf.Iteration)
+	check(err)
+	enc := json.NewEncoder(wt)
+	for d, ratings := range predicted {
+		check(enc.Encode(docRatings{Doc: d, Meta: docs[d].Meta, Aspects: aspects, Ratings: ratings}))
+	}
+}
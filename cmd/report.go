@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+
+	"github.com/yuui-ro/mglda"
+)
+
+// report writes a static HTML report of the model in model_path.
+func report(args []string) {
+	opt := mglda.DefaultReportOptions()
+	conf := parseArgs("report", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&opt.Title, "title", opt.Title, "Title of the report")
+		fs.IntVar(&opt.Examples, "examples", opt.Examples, "Number of example documents")
+	})
+	check(conf.validate())
+	opt.TopWords = conf.topWordsOptions()
+
+	m, vocabulary := loadModel(conf.ModelPath)
+
+	out := createOutput(conf.OutPath)
+	defer checkClose(out)
+	wt := bufio.NewWriter(out)
+	defer wt.Flush()
+	check(mglda.WriteReport(wt, m, vocabulary, opt))
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/yuui-ro/mglda"
+)
+
+func train(args []string) {
+	conf := parseArgs("train", args, nil)
+	check(conf.validate())
+
+	data := Data{}
+	check(data.parse(conf.DataPath))
+	uW := conf.vocabularySize(&data)
+	docs := data.Docs
+	check(mglda.ValidateDocuments(docs, uW))
+	conf.windows(docs, conf.T)
+
+	if conf.Trainer != "gibbs" {
+		trainVariational(conf, &data, uW)
+		return
+	}
+
+	m := newModel(conf, uW, &docs)
+	if conf.LabelsPath != "" {
+		check(m.SetLabels(readLabels(conf.LabelsPath)))
+	}
+	if conf.SeedsPath != "" {
+		fp, err := os.Open(conf.SeedsPath)
+		check(err)
+		seeds, err := mglda.ReadSeeds(fp)
+		fp.Close()
+		check(err)
+		check(m.SetSeeds(seeds, data.Vocabulary))
+	}
+	if conf.Aspects > 0 {
+		check(m.EnableRatings(conf.Aspects, conf.RatingLevels, conf.RatingRate, conf.RatingL2))
+	}
+	if conf.HDP {
+		check(m.EnableHDP(conf.HDPAlpha, conf.HDPGamma))
+	}
+	if conf.Covariates != "" {
+		check(m.EnableCovariates(strings.Split(conf.Covariates, ","), conf.CovariateSigma, conf.CovariateRate))
+	}
+	if conf.LearnWindow {
+		check(m.LearnWindows(conf.WindowPrior))
+	}
+	if conf.WindowPosition != 1 || conf.LearnGamma {
+		check(m.SetWindowGamma(mglda.PositionalGamma(conf.Gamma, conf.WindowPosition, conf.T), conf.LearnGamma))
+	}
+	if conf.Chains > 1 {
+		trainChains(conf, m, data.Vocabulary)
+		return
+	}
+	wt, closeOutput := openOutput(conf)
+	defer closeOutput()
+	if conf.OutputFormat == "text" {
+		mglda.LearningWithOptions(m, conf.Iteration, data.Vocabulary, conf.topWordsOptions(), wt)
+		writeCoherence(conf, m, wt)
+	} else {
+		m.Train(conf.Iteration)
+		writeStructured(conf, m, data.Vocabulary, wt)
+	}
+
+	if conf.ModelPath != "" {
+		saveModel(conf.ModelPath, m, data.Vocabulary)
+	}
+}
+
+// trainSVI trains by stochastic variational inference for iteration epochs.
+func trainSVI(conf *Configuration, docs *[]mglda.Document, uW int) *mglda.MGLDA {
+	s := mglda.NewSVI(conf.GlobalK, conf.LocalK, conf.Gamma,
+		conf.GlobalAlpha, conf.LocalAlpha,
+		conf.GlobalAlphaMix, conf.LocalAlphaMix,
+		conf.GlobalBeta, conf.LocalBeta,
+		conf.T, uW, len(*docs), conf.sviOptions(), conf.Seed)
+	s.Train(*docs, conf.Iteration)
+	return s.Model(docs)
+}
+
+// trainCVB0 trains by CVB0 for at most iteration sweeps.
+func trainCVB0(conf *Configuration, docs *[]mglda.Document, uW int) *mglda.MGLDA {
+	c := mglda.NewCVB0(conf.GlobalK, conf.LocalK, conf.Gamma,
+		conf.GlobalAlpha, conf.LocalAlpha,
+		conf.GlobalAlphaMix, conf.LocalAlphaMix,
+		conf.GlobalBeta, conf.LocalBeta,
+		conf.T, uW, docs, conf.Seed)
+	glog.Infof("cvb0: %d sweeps", c.Train(conf.Iteration, conf.Tolerance))
+	return c.Model()
+}
+
+// trainVariational trains with the configured variational trainer and
+// writes and saves the model with every word assigned to its most
+// responsible window and topic.
+func trainVariational(conf *Configuration, data *Data, uW int) {
+	docs := data.Docs
+	var m *mglda.MGLDA
+	if conf.Trainer == "svi" {
+		m = trainSVI(conf, &docs, uW)
+	} else {
+		m = trainCVB0(conf, &docs, uW)
+	}
+	if conf.LabelsPath != "" {
+		check(m.SetLabels(readLabels(conf.LabelsPath)))
+	}
+
+	wt, closeOutput := openOutput(conf)
+	defer closeOutput()
+	if conf.OutputFormat == "text" {
+		mglda.WriteTopics(m, data.Vocabulary, conf.topWordsOptions(), wt)
+		writeCoherence(conf, m, wt)
+	} else {
+		writeStructured(conf, m, data.Vocabulary, wt)
+	}
+
+	if conf.ModelPath != "" {
+		saveModel(conf.ModelPath, m, data.Vocabulary)
+	}
+}
+
+// trainChains trains chains independent chains of m in parallel, writes the
+// topics of the first chain with the convergence diagnostics of all of them,
+// and saves the first chain.
+func trainChains(conf *Configuration, m *mglda.MGLDA, vocabulary []string) {
+	c, err := mglda.NewChains(m, conf.Chains, conf.Seed)
+	check(err)
+	c.Train(conf.Iteration)
+	burnin := conf.ChainBurnin
+	if burnin < 0 {
+		burnin = conf.Iteration / 2
+	}
+	diag := c.Diagnose(burnin)
+	first := c.Models[0]
+
+	wt, closeOutput := openOutput(conf)
+	defer closeOutput()
+	if conf.OutputFormat == "text" {
+		mglda.WriteTopics(first, vocabulary, conf.topWordsOptions(), wt)
+		writeCoherence(conf, first, wt)
+		writeChainDiagnostics(wt, diag)
+	} else {
+		writeStructured(conf, first, vocabulary, wt)
+		glog.Infof("chains: max rhat %.3f, loglikelihood rhat %.3f, ess %.1f",
+			diag.MaxRHat, diag.LogLikelihood.RHat, diag.LogLikelihood.ESS)
+	}
+
+	if conf.ModelPath != "" {
+		saveModel(conf.ModelPath, first, vocabulary)
+	}
+}
+
+// writeChainDiagnostics writes the convergence diagnostics of diag as text.
+// R-hat above 1.1 is commonly taken as a sign that sampling should go on.
+func writeChainDiagnostics(wt io.Writer, diag mglda.ChainDiagnostics) {
+	fmt.Fprintf(wt, "==== convergence of %d chains over %d draws ====\n", diag.Chains, diag.Draws)
+	fmt.Fprintf(wt, "loglikelihood: rhat %.3f, ess %.1f\n", diag.LogLikelihood.RHat, diag.LogLikelihood.ESS)
+	for _, t := range diag.Topics {
+		name := ""
+		if t.Name != "" {
+			name = " [" + t.Name + "]"
+		}
+		fmt.Fprintf(wt, "%s topic %d%s: rhat %.3f, ess %.1f, js %.3f\n",
+			t.Kind, t.Topic, name, t.RHat, t.ESS, t.JS)
+	}
+	verdict := "converged"
+	if !(diag.MaxRHat < 1.1) {
+		verdict = "not converged"
+	}
+	fmt.Fprintf(wt, "max rhat: %.3f (%s)\n", diag.MaxRHat, verdict)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+
+	"github.com/yuui-ro/mglda"
+)
+
+// trends writes the prevalence of every topic of the model in model_path
+// per time bucket, over the documents with a time.
+func trends(args []string) {
+	var bucket string
+	conf := parseArgs("trends", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&bucket, "bucket", string(mglda.Month), "Length of a time bucket: day, week, month or year")
+	})
+	check(conf.validate())
+
+	m, _ := loadModel(conf.ModelPath)
+	series, err := m.TopicTrends(mglda.TimeBucket(bucket))
+	check(err)
+
+	out := createOutput(conf.OutPath)
+	defer checkClose(out)
+	wt := bufio.NewWriter(out)
+	defer wt.Flush()
+	switch conf.OutputFormat {
+	case "json":
+		enc := json.NewEncoder(wt)
+		enc.SetIndent("", "  ")
+		check(enc.Encode(series))
+	case "tsv":
+		check(mglda.WriteTrendsCSV(wt, series, '\t'))
+	default:
+		check(mglda.WriteTrendsCSV(wt, series, ','))
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/yuui-ro/mglda"
+)
+
+// update adds the documents of data_path to the model in model_path, trains
+// them incrementally and saves the model in place.
+func update(args []string) {
+	opt := mglda.UpdateOptions{}
+	conf := parseArgs("update", args, func(fs *flag.FlagSet) {
+		fs.Float64Var(&opt.OldSample, "old_sample", 0, "Fraction of the old documents resampled along with the new ones")
+	})
+	check(conf.validate())
+	if opt.OldSample < 0 || opt.OldSample > 1 {
+		check(fmt.Errorf("old_sample must be in [0, 1]"))
+	}
+	opt.Iteration = conf.Iteration
+
+	m, vocabulary := loadModel(conf.ModelPath)
+	seed(m, conf.Seed)
+	data := Data{}
+	check(data.parse(conf.DataPath))
+	docs, vocabulary := data.encode(m, vocabulary, mglda.OOVPolicy(conf.OOV))
+	conf.windows(docs, m.T)
+
+	m.Update(docs, opt)
+	saveModel(conf.ModelPath, m, vocabulary)
+}
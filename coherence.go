@@ -0,0 +1,260 @@
+package mglda
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CooccurrenceUnit is the context in which two words co-occur.
+type CooccurrenceUnit int
+
+const (
+	DocumentUnit CooccurrenceUnit = iota
+	SentenceUnit
+)
+
+// npmiEpsilon keeps the logarithms finite for pairs that never co-occur.
+const npmiEpsilon = 1e-12
+
+// TopicCoherence holds the coherence scores of the top words of one topic.
+type TopicCoherence struct {
+	Kind  TopicKind `json:"kind"`
+	Topic int       `json:"topic"`
+	Name  string    `json:"name,omitempty"`
+	UMass float64   `json:"umass"`
+	NPMI  float64   `json:"npmi"`
+	CV    float64   `json:"cv"`
+}
+
+// cooccurrence counts the contexts containing each word of interest and
+// each pair of them.
+type cooccurrence struct {
+	contexts float64
+	single   map[int]float64
+	pair     map[[2]int]float64
+}
+
+func newCooccurrence(docs []Document, topWords [][]int, unit CooccurrenceUnit) *cooccurrence {
+	interest := map[int]bool{}
+	for _, words := range topWords {
+		for _, wd := range words {
+			interest[wd] = true
+		}
+	}
+
+	c := &cooccurrence{single: map[int]float64{}, pair: map[[2]int]float64{}}
+	count := func(words map[int]bool) {
+		c.contexts++
+		ws := []int{}
+		for wd := range words {
+			c.single[wd]++
+			ws = append(ws, wd)
+		}
+		sort.Ints(ws)
+		for i := 0; i < len(ws); i++ {
+			for j := i + 1; j < len(ws); j++ {
+				c.pair[[2]int{ws[i], ws[j]}]++
+			}
+		}
+	}
+	for _, doc := range docs {
+		if doc.State == Holdout {
+			continue
+		}
+		words := map[int]bool{}
+		for _, sent := range doc.Sentenses {
+			for _, wd := range sent.Words {
+				if interest[wd] {
+					words[wd] = true
+				}
+			}
+			if unit == SentenceUnit {
+				count(words)
+				words = map[int]bool{}
+			}
+		}
+		if unit == DocumentUnit {
+			count(words)
+		}
+	}
+	return c
+}
+
+func (c *cooccurrence) joint(wi, wj int) float64 {
+	if wi > wj {
+		wi, wj = wj, wi
+	}
+	return c.pair[[2]int{wi, wj}]
+}
+
+func (c *cooccurrence) npmi(wi, wj int) float64 {
+	if c.contexts == 0 {
+		return 0
+	}
+	joint := c.joint(wi, wj)
+	if joint == c.contexts {
+		// both words are in every context
+		return 1
+	}
+	pij := math.Max(joint/c.contexts, npmiEpsilon)
+	pi := c.single[wi] / c.contexts
+	pj := c.single[wj] / c.contexts
+	if pi == 0 || pj == 0 {
+		return 0
+	}
+	return math.Log(pij/(pi*pj)) / -math.Log(pij)
+}
+
+// umass is the UMass coherence of words ordered by decreasing probability.
+func (c *cooccurrence) umass(words []int) float64 {
+	var score float64
+	for i := 1; i < len(words); i++ {
+		for j := 0; j < i; j++ {
+			if c.single[words[j]] == 0 {
+				continue
+			}
+			score += math.Log((c.joint(words[i], words[j]) + 1) / c.single[words[j]])
+		}
+	}
+	return score
+}
+
+// npmiMean is the average NPMI over all pairs of words.
+func (c *cooccurrence) npmiMean(words []int) float64 {
+	var score float64
+	pairs := 0
+	for i := 0; i < len(words); i++ {
+		for j := i + 1; j < len(words); j++ {
+			score += c.npmi(words[i], words[j])
+			pairs++
+		}
+	}
+	if pairs == 0 {
+		return 0
+	}
+	return score / float64(pairs)
+}
+
+// cv is the C_V coherence: the mean cosine similarity between the NPMI
+// context vector of each word and that of the whole word set.
+func (c *cooccurrence) cv(words []int) float64 {
+	if len(words) == 0 {
+		return 0
+	}
+	vectors := make([][]float64, len(words))
+	total := make([]float64, len(words))
+	for i, wi := range words {
+		vectors[i] = make([]float64, len(words))
+		for j, wj := range words {
+			if i == j {
+				vectors[i][j] = 1
+			} else {
+				vectors[i][j] = c.npmi(wi, wj)
+			}
+			total[j] += vectors[i][j]
+		}
+	}
+	var score float64
+	for _, v := range vectors {
+		score += cosine(v, total)
+	}
+	return score / float64(len(words))
+}
+
+func cosine(a, b []float64) float64 {
+	var ab, aa, bb float64
+	for i := range a {
+		ab += a[i] * b[i]
+		aa += a[i] * a[i]
+		bb += b[i] * b[i]
+	}
+	if aa == 0 || bb == 0 {
+		return 0
+	}
+	return ab / math.Sqrt(aa*bb)
+}
+
+// UMassCoherence returns the UMass coherence of each list of top words,
+// counting co-occurrences in docs. Each list must be ordered by decreasing
+// probability.
+func UMassCoherence(docs []Document, topWords [][]int, unit CooccurrenceUnit) []float64 {
+	c := newCooccurrence(docs, topWords, unit)
+	scores := make([]float64, len(topWords))
+	for i, words := range topWords {
+		scores[i] = c.umass(words)
+	}
+	return scores
+}
+
+// NPMICoherence returns the average pairwise NPMI of each list of top words
+// against the reference corpus docs.
+func NPMICoherence(docs []Document, topWords [][]int, unit CooccurrenceUnit) []float64 {
+	c := newCooccurrence(docs, topWords, unit)
+	scores := make([]float64, len(topWords))
+	for i, words := range topWords {
+		scores[i] = c.npmiMean(words)
+	}
+	return scores
+}
+
+// CVCoherence returns the C_V coherence of each list of top words against
+// the reference corpus docs.
+func CVCoherence(docs []Document, topWords [][]int, unit CooccurrenceUnit) []float64 {
+	c := newCooccurrence(docs, topWords, unit)
+	scores := make([]float64, len(topWords))
+	for i, words := range topWords {
+		scores[i] = c.cv(words)
+	}
+	return scores
+}
+
+// Coherence scores the top n words of every global and local topic. UMass
+// coherence counts co-occurrences in the training documents of m; NPMI and
+// C_V use reference, or the training documents if reference is nil.
+func (m *MGLDA) Coherence(n int, reference []Document, unit CooccurrenceUnit) []TopicCoherence {
+	phiGl, phiLoc := m.WordDist()
+	topGl := topWordIDs(m.GlobalK, phiGl.RowCopy, n)
+	topLoc := topWordIDs(m.LocalK, phiLoc.RowCopy, n)
+	top := append(append([][]int{}, topGl...), topLoc...)
+	if reference == nil {
+		reference = *m.Docs
+	}
+
+	umass := UMassCoherence(*m.Docs, top, unit)
+	npmi := NPMICoherence(reference, top, unit)
+	cv := CVCoherence(reference, top, unit)
+	result := make([]TopicCoherence, len(top))
+	for i := range top {
+		result[i] = TopicCoherence{Kind: Global, Topic: i, UMass: umass[i], NPMI: npmi[i], CV: cv[i]}
+		if i >= m.GlobalK {
+			result[i].Kind = Local
+			result[i].Topic = i - m.GlobalK
+		}
+		result[i].Name = m.Labels.Name(result[i].Kind, result[i].Topic)
+	}
+	return result
+}
+
+// WriteCoherence writes one line per topic and the averages per kind.
+func WriteCoherence(wt *bufio.Writer, coherence []TopicCoherence) {
+	sums := map[TopicKind]*TopicCoherence{Global: {}, Local: {}}
+	counts := map[TopicKind]int{}
+	for _, c := range coherence {
+		wt.WriteString(fmt.Sprintf("-- %s topic: %d%s umass: %f npmi: %f cv: %f\n",
+			c.Kind, c.Topic, nameSuffix(c.Name), c.UMass, c.NPMI, c.CV))
+		sums[c.Kind].UMass += c.UMass
+		sums[c.Kind].NPMI += c.NPMI
+		sums[c.Kind].CV += c.CV
+		counts[c.Kind]++
+	}
+	for _, kind := range []TopicKind{Global, Local} {
+		if counts[kind] == 0 {
+			continue
+		}
+		n := float64(counts[kind])
+		wt.WriteString(fmt.Sprintf("-- %s average umass: %f npmi: %f cv: %f\n",
+			kind, sums[kind].UMass/n, sums[kind].NPMI/n, sums[kind].CV/n))
+	}
+}
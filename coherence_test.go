@@ -0,0 +1,46 @@
+package mglda
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var coherenceDocs = []Document{
+	{Sentenses: []Sentense{{Words: []int{0}}, {Words: []int{1}}}},
+	{Sentenses: []Sentense{{Words: []int{0}}}},
+	{Sentenses: []Sentense{{Words: []int{1, 2}}}},
+}
+
+func TestUMassCoherence(t *testing.T) {
+	scores := UMassCoherence(coherenceDocs, [][]int{{0, 1}, {2, 1}}, DocumentUnit)
+	assert.InDelta(t, 0, scores[0], 1e-9)
+	assert.InDelta(t, math.Log(2), scores[1], 1e-9)
+
+	scores = UMassCoherence(coherenceDocs, [][]int{{0, 1}}, SentenceUnit)
+	assert.InDelta(t, math.Log(0.5), scores[0], 1e-9)
+}
+
+func TestNPMICoherence(t *testing.T) {
+	scores := NPMICoherence(coherenceDocs, [][]int{{0, 1}}, DocumentUnit)
+	assert.InDelta(t, math.Log(0.75)/math.Log(3), scores[0], 1e-6)
+
+	always := []Document{{Sentenses: []Sentense{{Words: []int{0, 1}}}}}
+	assert.InDelta(t, 1, NPMICoherence(always, [][]int{{0, 1}}, DocumentUnit)[0], 1e-9)
+	never := []Document{{Sentenses: []Sentense{{Words: []int{0}}}}, {Sentenses: []Sentense{{Words: []int{1}}}}}
+	assert.True(t, NPMICoherence(never, [][]int{{0, 1}}, DocumentUnit)[0] < -0.9)
+
+	scores = CVCoherence(coherenceDocs, [][]int{{0, 1}, {2}}, DocumentUnit)
+	assert.True(t, scores[0] > 0 && scores[0] <= 1)
+	assert.InDelta(t, 1, scores[1], 1e-9)
+}
+
+func TestMGLDACoherence(t *testing.T) {
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &docs)
+	coherence := m.Coherence(5, nil, SentenceUnit)
+	assert.Equal(t, 6, len(coherence))
+	assert.Equal(t, Local, coherence[4].Kind)
+	assert.Equal(t, 0, coherence[4].Topic)
+}
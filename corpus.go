@@ -0,0 +1,481 @@
+package mglda
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// sentenceMark separates sentences in the sentence-marked variants of the
+// bag-of-words formats and in MALLET text, the same convention mkmgldafile
+// uses for its input.
+const sentenceMark = "|"
+
+// splitSentences splits a bag of words into sentences of sentenceSize words.
+// If sentenceSize is not positive the whole bag becomes a single sentence.
+func splitSentences(words []int, sentenceSize int) []Sentense {
+	if len(words) == 0 {
+		return []Sentense{}
+	}
+	if sentenceSize <= 0 {
+		return []Sentense{{Words: words}}
+	}
+	sentenses := []Sentense{}
+	for i := 0; i < len(words); i += sentenceSize {
+		j := i + sentenceSize
+		if j > len(words) {
+			j = len(words)
+		}
+		sentenses = append(sentenses, Sentense{Words: words[i:j]})
+	}
+	return sentenses
+}
+
+// wordCounts returns the distinct word ids of words in ascending order
+// together with their counts.
+func wordCounts(words []int) ([]int, map[int]int) {
+	counts := map[int]int{}
+	ids := []int{}
+	for _, wd := range words {
+		if counts[wd] == 0 {
+			ids = append(ids, wd)
+		}
+		counts[wd]++
+	}
+	sort.Ints(ids)
+	return ids, counts
+}
+
+func allWords(doc *Document) []int {
+	words := []int{}
+	for _, s := range doc.Sentenses {
+		words = append(words, s.Words...)
+	}
+	return words
+}
+
+// ReadUCI reads a corpus in the UCI bag-of-words format. docword holds the
+// D, W and NNZ header lines followed by "docID wordID count" triples, both ids
+// starting from 1. vocab holds one word per line and may be nil.
+//
+// Bag-of-words data carries no sentence boundaries, so the words of each
+// document are split into sentences of sentenceSize words (or kept as one
+// sentence if sentenceSize is not positive). The sentence-marked variant
+// with "docID sentenceID wordID count" lines is also accepted, in which case
+// sentenceSize is ignored.
+func ReadUCI(docword, vocab io.Reader, sentenceSize int) ([]Document, []string, error) {
+	scanner := bufio.NewScanner(docword)
+	header := []int{}
+	for len(header) < 3 && scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("uci: invalid header line %q", line)
+		}
+		header = append(header, n)
+	}
+	if len(header) < 3 {
+		return nil, nil, fmt.Errorf("uci: missing D, W, NNZ header")
+	}
+	numDocs, numWords := header[0], header[1]
+
+	bags := make([][]int, numDocs)
+	marked := make([]map[int][]int, numDocs)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 && len(fields) != 4 {
+			return nil, nil, fmt.Errorf("uci: invalid line %q", scanner.Text())
+		}
+		vals, err := atois(fields)
+		if err != nil {
+			return nil, nil, fmt.Errorf("uci: invalid line %q", scanner.Text())
+		}
+		d, wd, count := vals[0]-1, vals[len(vals)-2]-1, vals[len(vals)-1]
+		if d < 0 || d >= numDocs {
+			return nil, nil, fmt.Errorf("uci: document id %d out of range", d+1)
+		}
+		if wd < 0 || wd >= numWords {
+			return nil, nil, fmt.Errorf("uci: word id %d out of range", wd+1)
+		}
+		if len(fields) == 3 {
+			for i := 0; i < count; i++ {
+				bags[d] = append(bags[d], wd)
+			}
+			continue
+		}
+		if marked[d] == nil {
+			marked[d] = map[int][]int{}
+		}
+		s := vals[1]
+		for i := 0; i < count; i++ {
+			marked[d][s] = append(marked[d][s], wd)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	docs := make([]Document, numDocs)
+	for d := range docs {
+		docs[d].Sentenses = splitSentences(bags[d], sentenceSize)
+		if marked[d] == nil {
+			continue
+		}
+		sids := []int{}
+		for s := range marked[d] {
+			sids = append(sids, s)
+		}
+		sort.Ints(sids)
+		for _, s := range sids {
+			docs[d].Sentenses = append(docs[d].Sentenses, Sentense{Words: marked[d][s]})
+		}
+	}
+
+	var vocabulary []string
+	if vocab != nil {
+		var err error
+		if vocabulary, err = ReadVocabulary(vocab); err != nil {
+			return nil, nil, err
+		}
+	}
+	return docs, vocabulary, nil
+}
+
+// WriteUCI writes docs in the UCI bag-of-words format. If markSentences is
+// true the sentence-marked variant is written so that ReadUCI restores the
+// sentences. vocab may be nil.
+func WriteUCI(docword, vocab io.Writer, docs []Document, vocabulary []string,
+	markSentences bool) error {
+	numWords := len(vocabulary)
+	lines := []string{}
+	for d := range docs {
+		if markSentences {
+			for s, sent := range docs[d].Sentenses {
+				ids, counts := wordCounts(sent.Words)
+				for _, wd := range ids {
+					lines = append(lines, fmt.Sprintf("%d %d %d %d", d+1, s+1, wd+1, counts[wd]))
+				}
+				numWords = maxWordID(ids, numWords)
+			}
+			continue
+		}
+		ids, counts := wordCounts(allWords(&docs[d]))
+		for _, wd := range ids {
+			lines = append(lines, fmt.Sprintf("%d %d %d", d+1, wd+1, counts[wd]))
+		}
+		numWords = maxWordID(ids, numWords)
+	}
+
+	wt := bufio.NewWriter(docword)
+	fmt.Fprintf(wt, "%d\n%d\n%d\n", len(docs), numWords, len(lines))
+	for _, line := range lines {
+		if _, err := wt.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := wt.Flush(); err != nil {
+		return err
+	}
+	if vocab != nil {
+		return WriteVocabulary(vocab, vocabulary)
+	}
+	return nil
+}
+
+func maxWordID(ids []int, numWords int) int {
+	if len(ids) > 0 && ids[len(ids)-1] >= numWords {
+		return ids[len(ids)-1] + 1
+	}
+	return numWords
+}
+
+// ReadLDAC reads a corpus in the LDA-C format, one document per line as
+// "N wordID:count wordID:count ..." with word ids starting from 0, where N
+// must be the number of wordID:count terms. vocab holds one word per line
+// and may be nil.
+//
+// Sentences are formed as in ReadUCI. In the sentence-marked variant a "|"
+// token closes a sentence, and sentenceSize is ignored for that line.
+func ReadLDAC(r, vocab io.Reader, sentenceSize int) ([]Document, []string, error) {
+	docs := []Document{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("ldac: invalid term count in line %d", len(docs)+1)
+		}
+		d := Document{Sentenses: []Sentense{}}
+		words := []int{}
+		marked := false
+		terms := 0
+		for _, f := range fields[1:] {
+			if f == sentenceMark {
+				marked = true
+				if len(words) > 0 {
+					d.Sentenses = append(d.Sentenses, Sentense{Words: words})
+				}
+				words = []int{}
+				continue
+			}
+			pair := strings.SplitN(f, ":", 2)
+			if len(pair) != 2 {
+				return nil, nil, fmt.Errorf("ldac: invalid term %q in line %d", f, len(docs)+1)
+			}
+			vals, err := atois(pair)
+			if err != nil || vals[0] < 0 {
+				return nil, nil, fmt.Errorf("ldac: invalid term %q in line %d", f, len(docs)+1)
+			}
+			for i := 0; i < vals[1]; i++ {
+				words = append(words, vals[0])
+			}
+			terms++
+		}
+		if terms != n {
+			return nil, nil, fmt.Errorf("ldac: %d terms in line %d, want %d", terms, len(docs)+1, n)
+		}
+		if marked {
+			if len(words) > 0 {
+				d.Sentenses = append(d.Sentenses, Sentense{Words: words})
+			}
+		} else {
+			d.Sentenses = splitSentences(words, sentenceSize)
+		}
+		docs = append(docs, d)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var vocabulary []string
+	if vocab != nil {
+		var err error
+		if vocabulary, err = ReadVocabulary(vocab); err != nil {
+			return nil, nil, err
+		}
+	}
+	return docs, vocabulary, nil
+}
+
+// WriteLDAC writes docs in the LDA-C format. If markSentences is true the
+// sentence-marked variant is written. vocab may be nil.
+func WriteLDAC(w, vocab io.Writer, docs []Document, vocabulary []string,
+	markSentences bool) error {
+	wt := bufio.NewWriter(w)
+	for d := range docs {
+		terms := []string{}
+		if markSentences {
+			for _, sent := range docs[d].Sentenses {
+				ids, counts := wordCounts(sent.Words)
+				for _, wd := range ids {
+					terms = append(terms, fmt.Sprintf("%d:%d", wd, counts[wd]))
+				}
+				terms = append(terms, sentenceMark)
+			}
+		} else {
+			ids, counts := wordCounts(allWords(&docs[d]))
+			for _, wd := range ids {
+				terms = append(terms, fmt.Sprintf("%d:%d", wd, counts[wd]))
+			}
+		}
+		n := len(terms)
+		if markSentences {
+			n -= len(docs[d].Sentenses)
+		}
+		line := strconv.Itoa(n)
+		if len(terms) > 0 {
+			line += " " + strings.Join(terms, " ")
+		}
+		if _, err := wt.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := wt.Flush(); err != nil {
+		return err
+	}
+	if vocab != nil {
+		return WriteVocabulary(vocab, vocabulary)
+	}
+	return nil
+}
+
+// malletLine matches MALLET's default line regex for text import:
+// instance name, label and data.
+var malletLine = regexp.MustCompile(`^(\S*)[\s,]*(\S*)[\s,]*(.*)$`)
+
+// ReadMallet reads a corpus in MALLET's one-instance-per-line text import
+// format, "name label text...". Words are separated by white space and the
+// vocabulary is built in order of first appearance. A word ending with '.',
+// '!' or '?', or a "|" token, closes a sentence.
+func ReadMallet(r io.Reader) ([]Document, []string, error) {
+	docs := []Document{}
+	vocabulary := []string{}
+	ids := map[string]int{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		match := malletLine.FindStringSubmatch(line)
+		d := Document{Sentenses: []Sentense{}}
+		words := []int{}
+		for _, tok := range strings.Fields(match[3]) {
+			closes := tok == sentenceMark || strings.ContainsAny(tok[len(tok)-1:], ".!?")
+			word := strings.TrimFunc(tok, unicode.IsPunct)
+			if word != "" && word != sentenceMark {
+				id, ok := ids[word]
+				if !ok {
+					id = len(vocabulary)
+					ids[word] = id
+					vocabulary = append(vocabulary, word)
+				}
+				words = append(words, id)
+			}
+			if closes && len(words) > 0 {
+				d.Sentenses = append(d.Sentenses, Sentense{Words: words})
+				words = []int{}
+			}
+		}
+		if len(words) > 0 {
+			d.Sentenses = append(d.Sentenses, Sentense{Words: words})
+		}
+		docs = append(docs, d)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return docs, vocabulary, nil
+}
+
+// WriteMallet writes docs in MALLET's text import format with instance name
+// "docN", label "-" and sentences separated by "|".
+func WriteMallet(w io.Writer, docs []Document, vocabulary []string) error {
+	wt := bufio.NewWriter(w)
+	for d := range docs {
+		sentenses := []string{}
+		for _, sent := range docs[d].Sentenses {
+			words := []string{}
+			for _, wd := range sent.Words {
+				if wd < 0 || wd >= len(vocabulary) {
+					return fmt.Errorf("mallet: word id %d out of vocabulary", wd)
+				}
+				words = append(words, vocabulary[wd])
+			}
+			sentenses = append(sentenses, strings.Join(words, " "))
+		}
+		line := fmt.Sprintf("doc%d\t-\t%s\n", d, strings.Join(sentenses, " "+sentenceMark+" "))
+		if _, err := wt.WriteString(line); err != nil {
+			return err
+		}
+	}
+	return wt.Flush()
+}
+
+func atois(fields []string) ([]int, error) {
+	vals := make([]int, len(fields))
+	for i, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+// ReadLines reads a corpus with one document per line and sentences
+// separated by "|", the input format of mkmgldafile. Each sentence is a list
+// of whitespace-separated word ids or, if tokens is true, of words. Words are
+// looked up in vocabulary; if vocabulary is nil it is built from the corpus.
+// Empty sentences are dropped.
+func ReadLines(r io.Reader, vocabulary []string, tokens bool) ([]Document, []string, error) {
+	grow := vocabulary == nil
+	ids := map[string]int{}
+	for i, word := range vocabulary {
+		ids[word] = i
+	}
+
+	docs := []Document{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		d := Document{}
+		for _, s := range strings.Split(line, sentenceMark) {
+			words := []int{}
+			for _, f := range strings.Fields(s) {
+				if !tokens {
+					id, err := strconv.Atoi(f)
+					if err != nil {
+						return nil, nil, fmt.Errorf("lines: invalid word id %q in line %d", f, len(docs)+1)
+					}
+					words = append(words, id)
+					continue
+				}
+				id, ok := ids[f]
+				if !ok {
+					if !grow {
+						return nil, nil, fmt.Errorf("lines: word %q in line %d not in vocabulary", f, len(docs)+1)
+					}
+					id = len(vocabulary)
+					ids[f] = id
+					vocabulary = append(vocabulary, f)
+				}
+				words = append(words, id)
+			}
+			// an empty sentence would still take a window position
+			if len(words) > 0 {
+				d.Sentenses = append(d.Sentenses, Sentense{Words: words})
+			}
+		}
+		docs = append(docs, d)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return docs, vocabulary, nil
+}
+
+// WriteLines writes docs in the format read by ReadLines. If tokens is true
+// words are written instead of word ids.
+func WriteLines(w io.Writer, docs []Document, vocabulary []string, tokens bool) error {
+	wt := bufio.NewWriter(w)
+	for d := range docs {
+		sentenses := []string{}
+		for _, sent := range docs[d].Sentenses {
+			words := []string{}
+			for _, wd := range sent.Words {
+				if tokens {
+					words = append(words, wordLabel(vocabulary, wd))
+				} else {
+					words = append(words, strconv.Itoa(wd))
+				}
+			}
+			sentenses = append(sentenses, strings.Join(words, " "))
+		}
+		if _, err := wt.WriteString(strings.Join(sentenses, " "+sentenceMark+" ") + "\n"); err != nil {
+			return err
+		}
+	}
+	return wt.Flush()
+}
@@ -0,0 +1,99 @@
+package mglda
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUCIRoundTrip(t *testing.T) {
+	var docword, vocab bytes.Buffer
+	err := WriteUCI(&docword, &vocab, docs, vocabulary, true)
+	assert.Nil(t, err)
+
+	newDocs, newVocabulary, err := ReadUCI(&docword, &vocab, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, vocabulary, newVocabulary)
+	assert.Equal(t, len(docs[0].Sentenses), len(newDocs[0].Sentenses))
+	for s, sent := range newDocs[0].Sentenses {
+		assert.Equal(t, len(docs[0].Sentenses[s].Words), len(sent.Words))
+	}
+}
+
+func TestReadUCISentenceSize(t *testing.T) {
+	in := "2\n3\n3\n1 1 4\n1 3 1\n2 2 2\n"
+	newDocs, newVocabulary, err := ReadUCI(strings.NewReader(in), nil, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, newVocabulary)
+	assert.Equal(t, 2, len(newDocs))
+	assert.Equal(t, 3, len(newDocs[0].Sentenses))
+	assert.Equal(t, []int{2}, newDocs[0].Sentenses[2].Words)
+	assert.Equal(t, []int{1, 1}, newDocs[1].Sentenses[0].Words)
+
+	_, _, err = ReadUCI(strings.NewReader("1\n3\n1\n1 4 1\n"), nil, 0)
+	assert.NotNil(t, err)
+}
+
+func TestLDACRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteLDAC(&buf, nil, docs, vocabulary, false)
+	assert.Nil(t, err)
+	newDocs, _, err := ReadLDAC(&buf, nil, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(newDocs[0].Sentenses))
+	assert.Equal(t, docs[0].NumberOfWords(), newDocs[0].NumberOfWords())
+
+	buf.Reset()
+	err = WriteLDAC(&buf, nil, docs, vocabulary, true)
+	assert.Nil(t, err)
+	newDocs, _, err = ReadLDAC(&buf, nil, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, len(docs[0].Sentenses), len(newDocs[0].Sentenses))
+	assert.Equal(t, docs[0].NumberOfWords(), newDocs[0].NumberOfWords())
+
+	// a truncated line declares more terms than it has
+	_, _, err = ReadLDAC(strings.NewReader("2 0:1 1:2\n3 0:1 2:1\n"), nil, 0)
+	assert.EqualError(t, err, "ldac: 2 terms in line 2, want 3")
+}
+
+func TestMalletRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteMallet(&buf, docs, vocabulary)
+	assert.Nil(t, err)
+	newDocs, newVocabulary, err := ReadMallet(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, len(docs[0].Sentenses), len(newDocs[0].Sentenses))
+	for s, sent := range newDocs[0].Sentenses {
+		for w, wd := range sent.Words {
+			assert.Equal(t, vocabulary[docs[0].Sentenses[s].Words[w]], newVocabulary[wd])
+		}
+	}
+
+	newDocs, newVocabulary, err = ReadMallet(strings.NewReader("d1 en Great phone. Bad battery!\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(newDocs[0].Sentenses))
+	assert.Equal(t, []string{"Great", "phone", "Bad", "battery"}, newVocabulary)
+}
+
+func TestLinesRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteLines(&buf, docs, vocabulary, true))
+	newDocs, newVocabulary, err := ReadLines(&buf, vocabulary, true)
+	assert.Nil(t, err)
+	assert.Equal(t, vocabulary, newVocabulary)
+	assert.Equal(t, docs[0].Sentenses, newDocs[0].Sentenses)
+
+	newDocs, newVocabulary, err = ReadLines(strings.NewReader("a b | c a\n"), nil, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, newVocabulary)
+	assert.Equal(t, []int{2, 0}, newDocs[0].Sentenses[1].Words)
+
+	newDocs, _, err = ReadLines(strings.NewReader("a b||c |\n"), nil, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []Sentense{{Words: []int{0, 1}}, {Words: []int{2}}}, newDocs[0].Sentenses)
+
+	_, _, err = ReadLines(strings.NewReader("a d\n"), []string{"a"}, true)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,239 @@
+package mglda
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Covariates makes the global topic prior of a document depend on its
+// metadata, as in Dirichlet-multinomial regression (Mimno and McCallum,
+// 2008): the prior weight of global topic z in document d is
+// exp(Lambda[z][0] + sum of Lambda[z][f+1] over the features f of d), where
+// every value of every field of Fields is one feature. Lambda is fitted to
+// the global topic counts by gradient ascent after every sweep of Train,
+// under a Gaussian prior with standard deviation Sigma.
+type Covariates struct {
+	Fields []string `json:"fields"`
+	// Features holds the features as field=value.
+	Features []string `json:"features"`
+	// Lambda is indexed by global topic and then intercept and feature.
+	Lambda [][]float64 `json:"lambda"`
+	Sigma  float64     `json:"sigma"`
+	Rate   float64     `json:"rate"`
+
+	// alpha caches the prior weights of the global topics in every
+	// document; nil after Lambda changes.
+	alpha [][]float64
+}
+
+// MetaValue returns the value of field of the metadata of doc as a string,
+// and whether doc has it. Values that are not strings are given in JSON.
+func (doc *Document) MetaValue(field string) (string, bool) {
+	v, ok := doc.Meta[field]
+	if !ok {
+		return "", false
+	}
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v), true
+	}
+	return string(b), true
+}
+
+// EnableCovariates makes the global topic prior depend on the values of
+// fields of the metadata of the documents. It starts from the symmetric
+// prior GlobalAlpha. sigma is the standard deviation of the prior of the
+// coefficients and rate the learning rate of their updates.
+func (m *MGLDA) EnableCovariates(fields []string, sigma, rate float64) error {
+	switch {
+	case len(fields) == 0:
+		return fmt.Errorf("covariates: no fields")
+	case sigma <= 0 || rate <= 0:
+		return fmt.Errorf("covariates: sigma and rate must be positive")
+	case m.HDP != nil:
+		return fmt.Errorf("covariates: not supported with an hdp")
+	}
+	seen := map[string]bool{}
+	features := []string{}
+	for _, doc := range *m.Docs {
+		for _, field := range fields {
+			if v, ok := doc.MetaValue(field); ok && !seen[field+"="+v] {
+				seen[field+"="+v] = true
+				features = append(features, field+"="+v)
+			}
+		}
+	}
+	sort.Strings(features)
+	c := &Covariates{Fields: fields, Features: features, Lambda: zeros2(m.GlobalK, len(features)+1),
+		Sigma: sigma, Rate: rate}
+	for z := range c.Lambda {
+		c.Lambda[z][0] = math.Log(m.GlobalAlpha)
+	}
+	m.Covariates = c
+	return nil
+}
+
+// checkCovariates verifies that loaded covariates match the global topics
+// of m.
+func checkCovariates(m *MGLDA, c *Covariates) error {
+	if len(c.Lambda) != m.GlobalK || c.Sigma <= 0 || c.Rate <= 0 {
+		return fmt.Errorf("model: covariates do not match %d global topics", m.GlobalK)
+	}
+	for _, row := range c.Lambda {
+		if len(row) != len(c.Features)+1 {
+			return fmt.Errorf("model: covariates do not match %d features", len(c.Features))
+		}
+	}
+	return nil
+}
+
+// features returns the indices into a row of Lambda of the features of doc;
+// values not seen when the covariates were enabled are ignored.
+func (c *Covariates) features(doc *Document) []int {
+	f := []int{}
+	for _, field := range c.Fields {
+		v, ok := doc.MetaValue(field)
+		if !ok {
+			continue
+		}
+		if i := sort.SearchStrings(c.Features, field+"="+v); i < len(c.Features) && c.Features[i] == field+"="+v {
+			f = append(f, i+1)
+		}
+	}
+	return f
+}
+
+// prior returns the prior weights of the global topics in document d.
+func (c *Covariates) prior(m *MGLDA, d int) []float64 {
+	for len(c.alpha) <= d {
+		doc := &(*m.Docs)[len(c.alpha)]
+		f := c.features(doc)
+		alpha := make([]float64, len(c.Lambda))
+		for z, lambda := range c.Lambda {
+			x := lambda[0]
+			for _, i := range f {
+				x += lambda[i]
+			}
+			alpha[z] = math.Exp(x)
+		}
+		c.alpha = append(c.alpha, alpha)
+	}
+	return c.alpha[d]
+}
+
+// update takes one gradient step on Lambda towards the coefficients that
+// maximize the likelihood of the global topic counts of the counted
+// documents, with the topic distributions integrated out, plus the log
+// prior of the coefficients.
+func (c *Covariates) update(m *MGLDA) {
+	grad := zeros2(len(c.Lambda), len(c.Features)+1)
+	for d, doc := range *m.Docs {
+		if doc.State == Holdout {
+			continue
+		}
+		alpha := c.prior(m, d)
+		var sum float64
+		for _, a := range alpha {
+			sum += a
+		}
+		common := digamma(sum) - digamma(sum+m.Ndgl.Get(d, 0))
+		f := append([]int{0}, c.features(&(*m.Docs)[d])...)
+		for z, a := range alpha {
+			g := a * (common + digamma(a+m.Ndglz.Get(d, z)) - digamma(a))
+			for _, i := range f {
+				grad[z][i] += g
+			}
+		}
+	}
+	for z := range c.Lambda {
+		for i := range c.Lambda[z] {
+			c.Lambda[z][i] += c.Rate * (grad[z][i] - c.Lambda[z][i]/(c.Sigma*c.Sigma))
+		}
+	}
+	c.alpha = nil
+}
+
+// MetaGroup holds the mean topic distributions of the documents sharing a
+// value of a metadata field.
+type MetaGroup struct {
+	Value     string    `json:"value"`
+	Documents int       `json:"documents"`
+	Global    []float64 `json:"global"`
+	Local     []float64 `json:"local"`
+}
+
+// AggregateByMeta returns the mean global and local topic distributions of
+// the counted documents per value of field, as in DocTopicDist, sorted by
+// value. Documents without field are left out.
+func (m *MGLDA) AggregateByMeta(field string) []MetaGroup {
+	groups := map[string]*MetaGroup{}
+	for d, doc := range *m.Docs {
+		if doc.State == Holdout {
+			continue
+		}
+		v, ok := doc.MetaValue(field)
+		if !ok {
+			continue
+		}
+		g := groups[v]
+		if g == nil {
+			g = &MetaGroup{Value: v, Global: make([]float64, m.GlobalK), Local: make([]float64, m.LocalK)}
+			groups[v] = g
+		}
+		gl, loc := m.DocTopicDist(d)
+		for z := range gl {
+			g.Global[z] += gl[z]
+		}
+		for z := range loc {
+			g.Local[z] += loc[z]
+		}
+		g.Documents++
+	}
+	result := []MetaGroup{}
+	for _, g := range groups {
+		for z := range g.Global {
+			g.Global[z] /= float64(g.Documents)
+		}
+		for z := range g.Local {
+			g.Local[z] /= float64(g.Documents)
+		}
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Value < result[j].Value })
+	return result
+}
+
+// WriteMetaGroupsCSV writes groups with one row per group and topic, named
+// by labels, separated by sep (',' for CSV, '\t' for TSV).
+func WriteMetaGroupsCSV(w io.Writer, groups []MetaGroup, labels TopicLabels, sep rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = sep
+	if err := cw.Write([]string{"value", "documents", "kind", "topic", "name", "share"}); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		for _, kind := range []TopicKind{Global, Local} {
+			shares := g.Global
+			if kind == Local {
+				shares = g.Local
+			}
+			for z, share := range shares {
+				row := []string{g.Value, strconv.Itoa(g.Documents), string(kind), strconv.Itoa(z),
+					labels.Name(kind, z), strconv.FormatFloat(share, 'g', -1, 64)}
+				if err := cw.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,63 @@
+package mglda
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCovariates(t *testing.T) {
+	d := reviews(20)
+	for i := range d {
+		d[i].Meta = map[string]interface{}{"category": "phones", "stars": float64(d[i].Ratings[0])}
+		if i%4 == 0 {
+			d[i].Meta["category"] = "books"
+		}
+	}
+	delete(d[1].Meta, "category")
+	m := NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d)
+	m.Train(2)
+
+	v, ok := d[0].MetaValue("stars")
+	assert.True(t, ok)
+	assert.Equal(t, "5", v)
+	_, ok = d[1].MetaValue("category")
+	assert.False(t, ok)
+
+	assert.NotNil(t, m.EnableCovariates(nil, 1, 0.01))
+	assert.NotNil(t, m.EnableCovariates([]string{"category"}, 0, 0.01))
+	before := m.conditional(0, 0, d[0].Sentenses[0].Words[0])
+	assert.Nil(t, m.EnableCovariates([]string{"category", "stars"}, 1, 0.01))
+	assert.Equal(t, []string{"category=books", "category=phones", "stars=1", "stars=5"}, m.Covariates.Features)
+	assert.InDeltaSlice(t, before, m.conditional(0, 0, d[0].Sentenses[0].Words[0]), 1e-12)
+	assert.NotNil(t, m.EnableHDP(1, 1))
+
+	m.Train(5)
+	assert.NotEqual(t, m.Covariates.Lambda[0][1], m.Covariates.Lambda[0][2])
+	gl, _ := m.DocTopicDist(0)
+	assert.InDelta(t, 1, sum(gl), 1e-9)
+	assert.Equal(t, []int{1, 4}, m.Covariates.features(&d[0]))
+	assert.Equal(t, []int{3}, m.Covariates.features(&d[1]))
+
+	groups := m.AggregateByMeta("category")
+	assert.Equal(t, 2, len(groups))
+	assert.Equal(t, "books", groups[0].Value)
+	assert.Equal(t, 5, groups[0].Documents)
+	assert.Equal(t, 14, groups[1].Documents)
+	assert.InDelta(t, 1, sum(groups[1].Global), 1e-9)
+	assert.InDelta(t, 1, sum(groups[1].Local), 1e-9)
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteMetaGroupsCSV(&buf, groups, nil, ','))
+	assert.Contains(t, buf.String(), "books,5,local,1,,")
+
+	buf.Reset()
+	assert.Nil(t, SaveModel(&buf, m, nil))
+	loaded, _, err := LoadModel(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, m.Covariates.Lambda, loaded.Covariates.Lambda)
+	assert.Equal(t, "books", (*loaded.Docs)[0].Meta["category"])
+	assert.InDeltaSlice(t, m.conditional(2, 0, d[2].Sentenses[0].Words[0]),
+		loaded.conditional(2, 0, d[2].Sentenses[0].Words[0]), 1e-12)
+}
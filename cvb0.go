@@ -0,0 +1,135 @@
+package mglda
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/skelterjohn/go.matrix"
+)
+
+// CVB0 trains MG-LDA by zero-order collapsed variational Bayes (Asuncion et
+// al., 2009). Every word keeps a responsibility over the windows and topics
+// it may be drawn from, and the counts of the model hold the expected counts
+// under them. A sweep replaces each responsibility by the normalized full
+// conditional of the Gibbs sampler given all other expected counts, so
+// training is deterministic once the responsibilities are initialised.
+type CVB0 struct {
+	m *MGLDA
+	// Resp holds the responsibility of every word, indexed by document,
+	// sentence and word, over the windows and for each window the global and
+	// then the local topics.
+	Resp [][][][]float64
+}
+
+// NewCVB0 returns a trainer for docs with responsibilities drawn from a
+// source seeded by seed, which leaves the default source untouched.
+func NewCVB0(globalK, localK int, gamma, globalAlpha, localAlpha,
+	globalAlphaMix, localAlphaMix, globalBeta, localBeta float64,
+	t, w int, docs *[]Document, seed int64) *CVB0 {
+	m := newMGLDA(globalK, localK, gamma, globalAlpha, localAlpha,
+		globalAlphaMix, localAlphaMix, globalBeta, localBeta, t, w, docs)
+	c := &CVB0{m: m, Resp: make([][][][]float64, len(*docs))}
+	rng := rand.New(rand.NewSource(seed))
+	for d, doc := range *docs {
+		m.allocDocument(d)
+		k := m.window(d) * (globalK + localK)
+		c.Resp[d] = make([][][]float64, len(doc.Sentenses))
+		for s, sent := range doc.Sentenses {
+			c.Resp[d][s] = make([][]float64, len(sent.Words))
+			for n, wd := range sent.Words {
+				resp := make([]float64, k)
+				for i := range resp {
+					resp[i] = rng.Float64()
+				}
+				c.Resp[d][s][n] = normalizeRow(resp)
+				if doc.State != Holdout {
+					c.add(d, s, wd, c.Resp[d][s][n], 1)
+				}
+			}
+		}
+	}
+	return c
+}
+
+// add adds sign times the responsibility resp of word wd of sentence s of
+// document d to the expected counts.
+func (c *CVB0) add(d, s, wd int, resp []float64, sign float64) {
+	for i, q := range resp {
+		v, r, z := c.m.decode(d, i)
+		c.m.count(d, s, wd, v, r, z, sign*q)
+	}
+}
+
+// Iterate runs one sweep over the active documents and returns the largest
+// change of a responsibility.
+func (c *CVB0) Iterate() float64 {
+	var change float64
+	for d, doc := range *c.m.Docs {
+		if doc.State != Active {
+			continue
+		}
+		for s, sent := range doc.Sentenses {
+			for n, wd := range sent.Words {
+				resp := c.Resp[d][s][n]
+				c.add(d, s, wd, resp, -1)
+				p := normalizeRow(c.m.conditional(d, s, wd))
+				for i := range p {
+					change = math.Max(change, math.Abs(p[i]-resp[i]))
+				}
+				c.Resp[d][s][n] = p
+				c.add(d, s, wd, p, 1)
+			}
+		}
+	}
+	return change
+}
+
+// Train runs sweeps until the largest change of a responsibility falls
+// below tolerance, or for at most iteration sweeps. It returns the number
+// of sweeps run.
+func (c *CVB0) Train(iteration int, tolerance float64) int {
+	for i := 0; i < iteration; i++ {
+		if c.Iterate() < tolerance {
+			return i + 1
+		}
+	}
+	return iteration
+}
+
+// WordDist returns the topic word distributions of the expected counts; see
+// MGLDA.WordDist.
+func (c *CVB0) WordDist() (*matrix.DenseMatrix, *matrix.DenseMatrix) {
+	return c.m.WordDist()
+}
+
+// DocTopicDist returns the topic distributions of document d under the
+// expected counts; see MGLDA.DocTopicDist.
+func (c *CVB0) DocTopicDist(d int) ([]float64, []float64) {
+	return c.m.DocTopicDist(d)
+}
+
+// SentenceLocalDist returns the local topic posterior of sentence s of
+// document d under the expected counts; see MGLDA.SentenceLocalDist.
+func (c *CVB0) SentenceLocalDist(d, s int) []float64 {
+	return c.m.SentenceLocalDist(d, s)
+}
+
+// Model returns an MGLDA with every word assigned to the window and topic of
+// its largest responsibility after the last sweep.
+func (c *CVB0) Model() *MGLDA {
+	src := c.m
+	m := newMGLDA(src.GlobalK, src.LocalK, src.Gamma, src.GlobalAlpha, src.LocalAlpha,
+		src.GlobalAlphaMix, src.LocalAlphaMix, src.GlobalBeta, src.LocalBeta, src.T, src.W, src.Docs)
+	for d, doc := range *m.Docs {
+		m.allocDocument(d)
+		for s, sent := range doc.Sentenses {
+			for n := range sent.Words {
+				m.Vdsn[d][s][n], m.Rdsn[d][s][n], m.Zdsn[d][s][n] = m.decode(d, argmax(c.Resp[d][s][n]))
+			}
+		}
+		if doc.State != Holdout {
+			m.loadDocument(d)
+		}
+	}
+	return m
+}
@@ -0,0 +1,43 @@
+package mglda
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCVB0(t *testing.T) {
+	d := reviews(20)
+	c := NewCVB0(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d, 1)
+	n := float64(20 * 15)
+	total := func() float64 {
+		return c.m.Nglz.Get(0, 0) + c.m.Nglz.Get(1, 0) + c.m.Nlocz.Get(0, 0) + c.m.Nlocz.Get(1, 0)
+	}
+	assert.InDelta(t, n, total(), 1e-9)
+
+	first := c.Iterate()
+	sweeps := c.Train(50, 1e-3)
+	assert.True(t, sweeps >= 1)
+	assert.True(t, c.Iterate() < first)
+	assert.InDelta(t, n, total(), 1e-6)
+	assert.InDelta(t, 1, sum(c.Resp[0][0][0]), 1e-9)
+
+	gl, loc := c.DocTopicDist(0)
+	assert.InDelta(t, 1, sum(gl), 1e-9)
+	assert.InDelta(t, 1, sum(loc), 1e-9)
+	assert.InDelta(t, 1, sum(c.SentenceLocalDist(0, 1)), 1e-9)
+	phiGl, _ := c.WordDist()
+	assert.Equal(t, 6, phiGl.Cols())
+
+	// the same seed gives the same result
+	d2 := reviews(20)
+	c2 := NewCVB0(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d2, 1)
+	c2.Iterate()
+	c2.Train(50, 1e-3)
+	c2.Iterate()
+	assert.Equal(t, c.Resp, c2.Resp)
+
+	m := c.Model()
+	assert.InDelta(t, n, m.Nglz.Get(0, 0)+m.Nglz.Get(1, 0)+m.Nlocz.Get(0, 0)+m.Nlocz.Get(1, 0), 1e-12)
+	m.Train(1)
+}
@@ -0,0 +1,249 @@
+package mglda
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+)
+
+// DiagnosticsOptions sets the thresholds for flagging topics.
+type DiagnosticsOptions struct {
+	// TopN is the number of top words used for Jaccard similarity and
+	// topic diversity.
+	TopN int
+	// DuplicateJS flags a pair of topics as duplicates if their
+	// Jensen-Shannon divergence is below it.
+	DuplicateJS float64
+	// UniformEntropy flags a topic as junk if the entropy of its word
+	// distribution, relative to that of the uniform distribution, is above it.
+	UniformEntropy float64
+	// MinTokens flags a topic as junk if fewer tokens are assigned to it.
+	MinTokens float64
+}
+
+// DefaultDiagnosticsOptions returns the thresholds used by the mglda command.
+func DefaultDiagnosticsOptions() DiagnosticsOptions {
+	return DiagnosticsOptions{TopN: 10, DuplicateJS: 0.2, UniformEntropy: 0.95, MinTokens: 10}
+}
+
+// TopicPair holds the similarity of two topics.
+type TopicPair struct {
+	KindA   TopicKind `json:"kind_a"`
+	A       int       `json:"a"`
+	NameA   string    `json:"name_a,omitempty"`
+	KindB   TopicKind `json:"kind_b"`
+	B       int       `json:"b"`
+	NameB   string    `json:"name_b,omitempty"`
+	JS      float64   `json:"js"`
+	Cosine  float64   `json:"cosine"`
+	Jaccard float64   `json:"jaccard"`
+}
+
+// TopicDiagnostic holds the quality indicators of one topic.
+type TopicDiagnostic struct {
+	Kind     TopicKind `json:"kind"`
+	Topic    int       `json:"topic"`
+	Name     string    `json:"name,omitempty"`
+	Tokens   float64   `json:"tokens"`
+	Entropy  float64   `json:"entropy"`
+	Nearest  TopicPair `json:"nearest"`
+	Uniform  bool      `json:"uniform"`
+	LowCount bool      `json:"low_count"`
+}
+
+// Junk tells whether the topic is too uniform or has too few tokens.
+func (t *TopicDiagnostic) Junk() bool {
+	return t.Uniform || t.LowCount
+}
+
+// DiagnosticsReport summarizes the diversity and redundancy of the topics.
+type DiagnosticsReport struct {
+	GlobalDiversity float64           `json:"global_diversity"`
+	LocalDiversity  float64           `json:"local_diversity"`
+	Diversity       float64           `json:"diversity"`
+	Topics          []TopicDiagnostic `json:"topics"`
+	Pairs           []TopicPair       `json:"pairs"`
+	Duplicates      []TopicPair       `json:"duplicates"`
+}
+
+// JensenShannon returns the Jensen-Shannon divergence of p and q in bits,
+// which lies in [0, 1].
+func JensenShannon(p, q []float64) float64 {
+	var js float64
+	for i := range p {
+		mi := (p[i] + q[i]) / 2
+		if p[i] > 0 {
+			js += p[i] * math.Log2(p[i]/mi)
+		}
+		if q[i] > 0 {
+			js += q[i] * math.Log2(q[i]/mi)
+		}
+	}
+	// rounding must not take it below zero
+	return math.Max(js/2, 0)
+}
+
+// Jaccard returns the Jaccard similarity of two word sets.
+func Jaccard(a, b []int) float64 {
+	set := map[int]bool{}
+	for _, wd := range a {
+		set[wd] = true
+	}
+	inter := 0
+	union := len(set)
+	for _, wd := range b {
+		if set[wd] {
+			inter++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(inter) / float64(union)
+}
+
+// TopicDiversity returns the fraction of unique words among the top words of
+// all topics.
+func TopicDiversity(topWords [][]int) float64 {
+	unique := map[int]bool{}
+	total := 0
+	for _, words := range topWords {
+		for _, wd := range words {
+			unique[wd] = true
+		}
+		total += len(words)
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(len(unique)) / float64(total)
+}
+
+// normalizedEntropy returns the entropy of p relative to the entropy of the
+// uniform distribution over the same support.
+func normalizedEntropy(p []float64) float64 {
+	if len(p) < 2 {
+		return 0
+	}
+	var h float64
+	for _, pi := range p {
+		if pi > 0 {
+			h -= pi * math.Log(pi)
+		}
+	}
+	return h / math.Log(float64(len(p)))
+}
+
+// normalizeRow returns p scaled to sum to one.
+func normalizeRow(p []float64) []float64 {
+	var sum float64
+	for _, pi := range p {
+		sum += pi
+	}
+	q := make([]float64, len(p))
+	for i, pi := range p {
+		if sum > 0 {
+			q[i] = pi / sum
+		}
+	}
+	return q
+}
+
+// Diagnostics compares every pair of topics within and across the global and
+// local topic sets and flags duplicate and junk topics.
+func (m *MGLDA) Diagnostics(opt DiagnosticsOptions) *DiagnosticsReport {
+	phiGl, phiLoc := m.WordDist()
+	topGl := topWordIDs(m.GlobalK, phiGl.RowCopy, opt.TopN)
+	topLoc := topWordIDs(m.LocalK, phiLoc.RowCopy, opt.TopN)
+
+	topics := []TopicDiagnostic{}
+	rows := [][]float64{}
+	top := [][]int{}
+	for i := 0; i < m.GlobalK; i++ {
+		topics = append(topics, TopicDiagnostic{Kind: Global, Topic: i,
+			Name: m.Labels.Name(Global, i), Tokens: m.Nglz.Get(i, 0)})
+		rows = append(rows, normalizeRow(phiGl.RowCopy(i)))
+		top = append(top, topGl[i])
+	}
+	for i := 0; i < m.LocalK; i++ {
+		topics = append(topics, TopicDiagnostic{Kind: Local, Topic: i,
+			Name: m.Labels.Name(Local, i), Tokens: m.Nlocz.Get(i, 0)})
+		rows = append(rows, normalizeRow(phiLoc.RowCopy(i)))
+		top = append(top, topLoc[i])
+	}
+
+	r := &DiagnosticsReport{
+		GlobalDiversity: TopicDiversity(topGl),
+		LocalDiversity:  TopicDiversity(topLoc),
+		Diversity:       TopicDiversity(top),
+		Pairs:           []TopicPair{},
+		Duplicates:      []TopicPair{},
+	}
+	for i := range topics {
+		topics[i].Entropy = normalizedEntropy(rows[i])
+		topics[i].Uniform = topics[i].Entropy > opt.UniformEntropy
+		topics[i].LowCount = topics[i].Tokens < opt.MinTokens
+		topics[i].Nearest.JS = math.Inf(1)
+	}
+	for i := range topics {
+		for j := i + 1; j < len(topics); j++ {
+			pair := TopicPair{
+				KindA:   topics[i].Kind,
+				A:       topics[i].Topic,
+				NameA:   topics[i].Name,
+				KindB:   topics[j].Kind,
+				B:       topics[j].Topic,
+				NameB:   topics[j].Name,
+				JS:      JensenShannon(rows[i], rows[j]),
+				Cosine:  cosine(rows[i], rows[j]),
+				Jaccard: Jaccard(top[i], top[j]),
+			}
+			r.Pairs = append(r.Pairs, pair)
+			if pair.JS < opt.DuplicateJS {
+				r.Duplicates = append(r.Duplicates, pair)
+			}
+			if pair.JS < topics[i].Nearest.JS {
+				topics[i].Nearest = pair
+			}
+			if pair.JS < topics[j].Nearest.JS {
+				topics[j].Nearest = pair
+			}
+		}
+	}
+	for i := range topics {
+		if math.IsInf(topics[i].Nearest.JS, 1) {
+			topics[i].Nearest = TopicPair{}
+		}
+	}
+	r.Topics = topics
+	return r
+}
+
+// WriteDiagnostics writes the report as text: the diversity scores, one line
+// per topic with its nearest neighbour, and the duplicate pairs.
+func WriteDiagnostics(wt *bufio.Writer, r *DiagnosticsReport) {
+	wt.WriteString(fmt.Sprintf("diversity: %f (global %f, local %f)\n",
+		r.Diversity, r.GlobalDiversity, r.LocalDiversity))
+	for _, t := range r.Topics {
+		other := fmt.Sprintf("%s %d%s", t.Nearest.KindA, t.Nearest.A, nameSuffix(t.Nearest.NameA))
+		if t.Nearest.KindA == t.Kind && t.Nearest.A == t.Topic {
+			other = fmt.Sprintf("%s %d%s", t.Nearest.KindB, t.Nearest.B, nameSuffix(t.Nearest.NameB))
+		}
+		flags := ""
+		if t.Uniform {
+			flags += " uniform"
+		}
+		if t.LowCount {
+			flags += " low-count"
+		}
+		wt.WriteString(fmt.Sprintf("-- %s topic: %d%s (%.0f words) entropy: %f nearest: %s js: %f cosine: %f jaccard: %f%s\n",
+			t.Kind, t.Topic, nameSuffix(t.Name), t.Tokens, t.Entropy, other,
+			t.Nearest.JS, t.Nearest.Cosine, t.Nearest.Jaccard, flags))
+	}
+	for _, p := range r.Duplicates {
+		wt.WriteString(fmt.Sprintf("duplicate: %s %d%s ~ %s %d%s js: %f cosine: %f jaccard: %f\n",
+			p.KindA, p.A, nameSuffix(p.NameA), p.KindB, p.B, nameSuffix(p.NameB), p.JS, p.Cosine, p.Jaccard))
+	}
+}
@@ -0,0 +1,29 @@
+package mglda
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimilarities(t *testing.T) {
+	assert.InDelta(t, 0, JensenShannon([]float64{0.5, 0.5}, []float64{0.5, 0.5}), 1e-9)
+	assert.InDelta(t, 1, JensenShannon([]float64{1, 0}, []float64{0, 1}), 1e-9)
+	assert.InDelta(t, 1.0/3, Jaccard([]int{0, 1}, []int{1, 2}), 1e-9)
+	assert.InDelta(t, 0.75, TopicDiversity([][]int{{0, 1}, {1, 2}}), 1e-9)
+	assert.InDelta(t, 1, normalizedEntropy([]float64{0.25, 0.25, 0.25, 0.25}), 1e-9)
+}
+
+func TestDiagnostics(t *testing.T) {
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &docs)
+	opt := DefaultDiagnosticsOptions()
+	opt.DuplicateJS = 1.1
+	r := m.Diagnostics(opt)
+	assert.Equal(t, 6, len(r.Topics))
+	assert.Equal(t, 15, len(r.Pairs))
+	assert.Equal(t, 15, len(r.Duplicates))
+	for _, topic := range r.Topics {
+		assert.Equal(t, topic.LowCount, topic.Tokens < opt.MinTokens)
+	}
+}
@@ -0,0 +1,322 @@
+package mglda
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/skelterjohn/go.matrix"
+)
+
+// topicCount returns the number of topics of the given kind.
+func (m *MGLDA) topicCount(kind TopicKind) (int, error) {
+	switch kind {
+	case Global:
+		return m.GlobalK, nil
+	case Local:
+		return m.LocalK, nil
+	}
+	return 0, fmt.Errorf("edit: invalid topic kind %q", kind)
+}
+
+// MergeTopics merges topic b of the given kind into topic a: the words of b
+// move to a, which takes over the seed words of b and keeps its own label,
+// and the topics after b move down by one. Under an HDP the top-level
+// weight of b is added to a, and with covariates the prior weight of a
+// becomes the sum of both at the intercept.
+func (m *MGLDA) MergeTopics(kind TopicKind, a, b int) error {
+	k, err := m.topicCount(kind)
+	if err != nil {
+		return err
+	}
+	if a < 0 || a >= k || b < 0 || b >= k || a == b {
+		return fmt.Errorf("edit: cannot merge %s topic %d into %d", kind, b, a)
+	}
+	from := []int{}
+	for z := 0; z < k; z++ {
+		if z != b {
+			from = append(from, z)
+		}
+	}
+	na := a
+	if a > b {
+		na--
+	}
+	if err := m.checkEdit(kind, from); err != nil {
+		return err
+	}
+
+	seeds := []SeedWord{}
+	for _, sw := range m.Seeds {
+		if sw.Kind == kind && sw.Topic == b {
+			sw.Topic = a
+		}
+		seeds = append(seeds, sw)
+	}
+	m.Seeds = seeds
+	var beta float64
+	var lambda []float64
+	if kind == Global && m.HDP != nil {
+		beta = m.HDP.Beta[b]
+	}
+	if kind == Global && m.Covariates != nil {
+		lambda = m.Covariates.Lambda[b]
+	}
+
+	to := func(d, z int) int {
+		if z == b {
+			return na
+		}
+		if z > b {
+			return z - 1
+		}
+		return z
+	}
+	m.editTopics(kind, from, to)
+	if beta > 0 {
+		// editTopics returned the weight of b to the unused topics
+		m.HDP.Beta[na] += beta
+		m.HDP.Beta[m.GlobalK] -= beta
+	}
+	if lambda != nil {
+		row := m.Covariates.Lambda[na]
+		row[0] = logAddition(row[0], lambda[0])
+		m.Covariates.alpha = nil
+	}
+	return nil
+}
+
+// DeleteTopic removes topic z of the given kind along with its label and
+// seed words; the topics after it move down by one. Every word of z is
+// sampled again from its full conditional over the remaining topics of
+// both kinds. Under an HDP the top-level weight of z returns to the unused
+// topics.
+func (m *MGLDA) DeleteTopic(kind TopicKind, z int) error {
+	k, err := m.topicCount(kind)
+	if err != nil {
+		return err
+	}
+	if z < 0 || z >= k {
+		return fmt.Errorf("edit: %s topic %d out of range", kind, z)
+	}
+	if k == 1 {
+		return fmt.Errorf("edit: cannot delete the only %s topic", kind)
+	}
+	from := []int{}
+	for i := 0; i < k; i++ {
+		if i != z {
+			from = append(from, i)
+		}
+	}
+	if err := m.checkEdit(kind, from); err != nil {
+		return err
+	}
+	m.editTopics(kind, from, func(d, i int) int {
+		switch {
+		case i == z:
+			return -1
+		case i > z:
+			return i - 1
+		}
+		return i
+	})
+	return nil
+}
+
+// SplitTopic splits topic z of the given kind in two: a random half of the
+// words of z moves to a new, unlabelled topic at the end, which is returned.
+// The new topic takes over the seed words and an even share of the prior of
+// z; refinement sweeps of Train then pull the halves apart.
+func (m *MGLDA) SplitTopic(kind TopicKind, z int) (int, error) {
+	k, err := m.topicCount(kind)
+	if err != nil {
+		return 0, err
+	}
+	if z < 0 || z >= k {
+		return 0, fmt.Errorf("edit: %s topic %d out of range", kind, z)
+	}
+	from := []int{}
+	for i := 0; i < k; i++ {
+		from = append(from, i)
+	}
+	from = append(from, -1)
+
+	r := m.random()
+	m.editTopics(kind, from, func(d, i int) int {
+		if i == z && r.Intn(2) == 0 {
+			return k
+		}
+		return i
+	})
+	if len(m.Seeds) > 0 {
+		seeds := append([]SeedWord{}, m.Seeds...)
+		for _, sw := range m.Seeds {
+			if sw.Kind == kind && sw.Topic == z {
+				sw.Topic = k
+				seeds = append(seeds, sw)
+			}
+		}
+		if err := m.setSeedWords(seeds); err != nil {
+			return 0, err
+		}
+	}
+	if kind == Global && m.HDP != nil {
+		m.HDP.Beta[z] /= 2
+		m.HDP.Beta[k] = m.HDP.Beta[z]
+	}
+	if kind == Global && m.Covariates != nil {
+		c := m.Covariates
+		c.Lambda[z][0] -= math.Ln2
+		c.Lambda[k] = append([]float64{}, c.Lambda[z]...)
+		c.alpha = nil
+	}
+	return k, nil
+}
+
+// checkEdit verifies that the topics of the given kind can be replaced by
+// topics taking over the prior of from[i], or none if -1. The rated aspects
+// of a rating model are the first local topics and must keep their ids.
+func (m *MGLDA) checkEdit(kind TopicKind, from []int) error {
+	if kind != Local || m.Ratings == nil {
+		return nil
+	}
+	for a := 0; a < m.Ratings.Aspects; a++ {
+		if a >= len(from) || from[a] != a {
+			return fmt.Errorf("edit: local topics of the %d rated aspects cannot move", m.Ratings.Aspects)
+		}
+	}
+	return nil
+}
+
+// editTopics replaces the topics of the given kind by len(from) topics.
+// New topic i takes over the label, seed words and prior of old topic
+// from[i], or starts without them if from[i] is -1. Every word of old topic
+// z of document d moves to topic to(d, z), which is called once per word, or
+// is sampled again if that is -1. The counts of all counted documents are
+// rebuilt.
+func (m *MGLDA) editTopics(kind TopicKind, from []int, to func(d, z int) int) {
+	r, k := globalTopic, len(from)
+	oldK := m.GlobalK
+	if kind == Local {
+		r, oldK = localTopic, m.LocalK
+	}
+	newID := make([]int, oldK)
+	for z := range newID {
+		newID[z] = -1
+	}
+	for i, z := range from {
+		if z >= 0 {
+			newID[z] = i
+		}
+	}
+
+	for d, doc := range *m.Docs {
+		if doc.State != Holdout {
+			m.unloadDocument(d)
+		}
+	}
+	if kind == Global {
+		m.Nglzw, m.Nglz = matrix.Zeros(k, m.W), matrix.Zeros(k, 1)
+		m.Ndglz = matrix.Zeros(m.Ndglz.Rows(), k)
+		if h := m.HDP; h != nil {
+			beta := make([]float64, k+1)
+			beta[k] = h.Beta[oldK]
+			for z, i := range newID {
+				if i < 0 {
+					beta[k] += h.Beta[z]
+				}
+			}
+			for i, z := range from {
+				if z >= 0 {
+					beta[i] = h.Beta[z]
+				}
+			}
+			h.Beta = beta
+		}
+		if c := m.Covariates; c != nil {
+			lambda := zeros2(k, len(c.Features)+1)
+			for i, z := range from {
+				if z >= 0 {
+					lambda[i] = c.Lambda[z]
+				} else {
+					lambda[i][0] = math.Log(m.GlobalAlpha)
+				}
+			}
+			c.Lambda, c.alpha = lambda, nil
+		}
+		m.GlobalK = k
+	} else {
+		m.Nloczw, m.Nlocz = matrix.Zeros(k, m.W), matrix.Zeros(k, 1)
+		for d := range m.Ndvlocz {
+			m.Ndvlocz[d] = matrix.Numbers(len(m.Ndvlocz[d]), k, m.Inflation).Arrays()
+		}
+		m.LocalK = k
+	}
+
+	labels := TopicLabels{}
+	for _, label := range m.Labels {
+		if label.Kind == kind {
+			if newID[label.Topic] < 0 {
+				continue
+			}
+			label.Topic = newID[label.Topic]
+		}
+		labels = append(labels, label)
+	}
+	m.Labels = labels
+	seeds, seen := []SeedWord{}, map[SeedWord]bool{}
+	for _, sw := range m.Seeds {
+		if sw.Kind == kind {
+			if newID[sw.Topic] < 0 {
+				continue
+			}
+			sw.Topic = newID[sw.Topic]
+		}
+		key := SeedWord{Kind: sw.Kind, Topic: sw.Topic, Word: sw.Word}
+		if !seen[key] {
+			seen[key] = true
+			seeds = append(seeds, sw)
+		}
+	}
+	if len(m.Seeds) > 0 {
+		if err := m.setSeedWords(seeds); err != nil {
+			panic(err)
+		}
+	}
+
+	// words to sample again are left out of the counts until then
+	type position struct{ d, s, w int }
+	resample := []position{}
+	for d, doc := range *m.Docs {
+		for s, sent := range doc.Sentenses {
+			for w, wd := range sent.Words {
+				if m.Rdsn[d][s][w] == r {
+					m.Zdsn[d][s][w] = to(d, m.Zdsn[d][s][w])
+					if m.Zdsn[d][s][w] < 0 {
+						m.Rdsn[d][s][w] = ""
+						resample = append(resample, position{d, s, w})
+						continue
+					}
+				}
+				if doc.State != Holdout {
+					m.count(d, s, wd, m.Vdsn[d][s][w], m.Rdsn[d][s][w], m.Zdsn[d][s][w], 1)
+				}
+			}
+		}
+	}
+	if m.Ratings != nil {
+		m.Ratings.refresh(m)
+	}
+	for _, p := range resample {
+		wd := (*m.Docs)[p.d].Sentenses[p.s].Words[p.w]
+		newV, newR, newZ := m.sample(p.d, p.s, wd)
+		if (*m.Docs)[p.d].State != Holdout {
+			m.count(p.d, p.s, wd, newV, newR, newZ, 1)
+			if m.Ratings != nil {
+				m.Ratings.move(p.d, newR, newZ, wd, 1)
+			}
+		}
+		m.Vdsn[p.d][p.s][p.w] = newV
+		m.Rdsn[p.d][p.s][p.w] = newR
+		m.Zdsn[p.d][p.s][p.w] = newZ
+	}
+}
@@ -0,0 +1,157 @@
+package mglda
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assertCounts verifies the counts of m against those rebuilt from its
+// assignments.
+func assertCounts(t *testing.T, m *MGLDA) {
+	rebuilt := newMGLDA(m.GlobalK, m.LocalK, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, m.T, m.W, m.Docs)
+	for d := range *m.Docs {
+		rebuilt.allocDocument(d)
+	}
+	rebuilt.Vdsn, rebuilt.Rdsn, rebuilt.Zdsn = m.Vdsn, m.Rdsn, m.Zdsn
+	for d, doc := range *m.Docs {
+		if doc.State != Holdout {
+			rebuilt.loadDocument(d)
+		}
+	}
+	assert.Equal(t, rebuilt.Nglzw.Array(), m.Nglzw.Array())
+	assert.Equal(t, rebuilt.Nglz.Array(), m.Nglz.Array())
+	assert.Equal(t, rebuilt.Ndglz.Array(), m.Ndglz.Array())
+	assert.Equal(t, rebuilt.Nloczw.Array(), m.Nloczw.Array())
+	assert.Equal(t, rebuilt.Nlocz.Array(), m.Nlocz.Array())
+	assert.Equal(t, rebuilt.Ndvlocz, m.Ndvlocz)
+	assert.Equal(t, rebuilt.Ndsv, m.Ndsv)
+}
+
+func TestEditTopics(t *testing.T) {
+	d := reviews(20)
+	d[19].State = Holdout
+	m := NewMGLDA(3, 3, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d)
+	m.SetSeed(1)
+	m.Train(5)
+	assert.Nil(t, m.SetLabels(TopicLabels{
+		{Kind: Global, Topic: 1, Name: "junk"},
+		{Kind: Local, Topic: 0, Name: "praise"},
+		{Kind: Local, Topic: 2, Name: "duplicate"},
+	}))
+	assert.Nil(t, m.setSeedWords([]SeedWord{{Kind: Local, Topic: 2, Word: 0, Beta: 1}}))
+	tokens := sum(m.Nglz.Array()) + sum(m.Nlocz.Array())
+
+	_, err := m.topicCount("other")
+	assert.NotNil(t, err)
+	assert.NotNil(t, m.MergeTopics(Local, 1, 1))
+	assert.NotNil(t, m.MergeTopics(Local, 0, 3))
+	assert.NotNil(t, m.DeleteTopic(Global, 3))
+
+	local0, local2 := m.Nlocz.Get(0, 0), m.Nlocz.Get(2, 0)
+	assert.Nil(t, m.MergeTopics(Local, 0, 2))
+	assert.Equal(t, 2, m.LocalK)
+	assert.Equal(t, local0+local2, m.Nlocz.Get(0, 0))
+	assert.Equal(t, "praise", m.Labels.Name(Local, 0))
+	assert.Equal(t, "", m.Labels.Name(Local, 1))
+	assert.Equal(t, []SeedWord{{Kind: Local, Topic: 0, Word: 0, Beta: 1}}, m.Seeds)
+	assert.Equal(t, 1.0, m.beta(Local, 0, 0))
+	assertCounts(t, m)
+
+	assert.Nil(t, m.DeleteTopic(Global, 1))
+	assert.Equal(t, 2, m.GlobalK)
+	assert.Equal(t, "", m.Labels.Name(Global, 1))
+	assert.Equal(t, tokens, sum(m.Nglz.Array())+sum(m.Nlocz.Array()))
+	for dd := range m.Zdsn {
+		for s := range m.Zdsn[dd] {
+			for w, z := range m.Zdsn[dd][s] {
+				assert.Contains(t, []string{globalTopic, localTopic}, m.Rdsn[dd][s][w])
+				if m.Rdsn[dd][s][w] == globalTopic {
+					assert.True(t, z < 2)
+				}
+			}
+		}
+	}
+	assertCounts(t, m)
+
+	global0 := m.Nglz.Get(0, 0)
+	z, err := m.SplitTopic(Global, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, z)
+	assert.Equal(t, 3, m.GlobalK)
+	assert.Equal(t, global0, m.Nglz.Get(0, 0)+m.Nglz.Get(2, 0))
+	both := false
+	for dd := 0; dd < m.Ndglz.Rows(); dd++ {
+		both = both || m.Ndglz.Get(dd, 0) > 0 && m.Ndglz.Get(dd, 2) > 0
+	}
+	assert.True(t, both)
+	assertCounts(t, m)
+
+	z, err = m.SplitTopic(Local, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, z)
+	assert.Contains(t, m.Seeds, SeedWord{Kind: Local, Topic: 2, Word: 0, Beta: 1})
+	assert.Equal(t, 1.0, m.beta(Local, 2, 0))
+	assertCounts(t, m)
+
+	m.Train(2)
+	var buf bytes.Buffer
+	assert.Nil(t, SaveModel(&buf, m, nil))
+	loaded, _, err := LoadModel(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, m.Labels, loaded.Labels)
+
+	single := NewMGLDA(1, 1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &[]Document{})
+	assert.NotNil(t, single.DeleteTopic(Local, 0))
+}
+
+func TestEditExtensions(t *testing.T) {
+	d := reviews(20)
+	m := NewMGLDA(3, 3, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d)
+	assert.Nil(t, m.EnableRatings(1, 5, 0.1, 0.01))
+	m.Train(3)
+	// the rated aspect is local topic 0 and keeps its id
+	assert.NotNil(t, m.MergeTopics(Local, 1, 0))
+	assert.NotNil(t, m.DeleteTopic(Local, 0))
+	assert.Nil(t, m.MergeTopics(Local, 0, 2))
+	assert.Equal(t, m.Ratings.docScores(m, 0), m.Ratings.scores[0])
+	_, err := m.SplitTopic(Local, 0)
+	assert.Nil(t, err)
+	assertCounts(t, m)
+
+	d = reviews(20)
+	m = NewMGLDA(3, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d)
+	assert.Nil(t, m.EnableHDP(1, 1))
+	m.Train(3)
+	_, err = m.SplitTopic(Global, 0)
+	assert.Nil(t, err)
+	assert.InDelta(t, 1, sum(m.HDP.Beta), 1e-9)
+	assert.Nil(t, m.MergeTopics(Global, 0, m.GlobalK-1))
+	assert.InDelta(t, 1, sum(m.HDP.Beta), 1e-9)
+	if m.GlobalK > 1 {
+		assert.Nil(t, m.DeleteTopic(Global, 0))
+		assert.InDelta(t, 1, sum(m.HDP.Beta), 1e-9)
+	}
+	assert.Equal(t, m.GlobalK+1, len(m.HDP.Beta))
+	assertCounts(t, m)
+
+	for i := range d {
+		d[i].Meta = map[string]interface{}{"good": i%2 == 0}
+	}
+	m = NewMGLDA(3, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d)
+	assert.Nil(t, m.EnableCovariates([]string{"good"}, 1, 0.01))
+	m.Train(2)
+	prior := m.Covariates.prior(m, 0)
+	_, err = m.SplitTopic(Global, 1)
+	assert.Nil(t, err)
+	split := m.Covariates.prior(m, 0)
+	assert.Equal(t, 4, len(split))
+	assert.InDelta(t, prior[1], split[1]+split[3], 1e-9)
+	assert.Nil(t, m.MergeTopics(Global, 1, 3))
+	assert.InDelta(t, prior[1], m.Covariates.prior(m, 0)[1], 1e-9)
+	assert.Nil(t, m.DeleteTopic(Global, 2))
+	assert.Equal(t, 2, len(m.Covariates.Lambda))
+	assert.Nil(t, checkCovariates(m, m.Covariates))
+	m.Train(1)
+}
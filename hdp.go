@@ -0,0 +1,249 @@
+package mglda
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/golang/glog"
+	"github.com/skelterjohn/go.matrix"
+)
+
+// HDP makes the global topics nonparametric: the global topic distribution
+// of every document is drawn from a Dirichlet process with concentration
+// Alpha around top-level weights Beta, which are drawn from a GEM
+// distribution with concentration Gamma (Teh et al., 2006). The sampler is
+// the direct assignment sampler: a word may open a new global topic, empty
+// global topics are removed after every sweep, and Beta is resampled from
+// the table counts. Local topics stay fixed at LocalK.
+type HDP struct {
+	Alpha float64 `json:"alpha"`
+	Gamma float64 `json:"gamma"`
+	// Beta holds the top-level weight of every global topic and, last, the
+	// weight of all unused topics.
+	Beta []float64 `json:"beta"`
+	// Topics holds the number of global topics after every sweep.
+	Topics []int `json:"topics,omitempty"`
+}
+
+// EnableHDP switches the global topics of m to an HDP with document
+// concentration alpha and top-level concentration gamma, starting from the
+// current GlobalK topics with uniform top-level weights.
+func (m *MGLDA) EnableHDP(alpha, gamma float64) error {
+	if alpha <= 0 || gamma <= 0 {
+		return fmt.Errorf("hdp: alpha and gamma must be positive")
+	}
+	if m.Covariates != nil {
+		return fmt.Errorf("hdp: not supported with covariates")
+	}
+	for _, sw := range m.Seeds {
+		if sw.Kind == Global {
+			return fmt.Errorf("hdp: global topics cannot be seeded")
+		}
+	}
+	beta := make([]float64, m.GlobalK+1)
+	for z := range beta {
+		beta[z] = 1 / float64(len(beta))
+	}
+	m.HDP = &HDP{Alpha: alpha, Gamma: gamma, Beta: beta}
+	return nil
+}
+
+// checkHDP verifies that a loaded HDP matches the global topics of m.
+func checkHDP(m *MGLDA, h *HDP) error {
+	if h.Alpha <= 0 || h.Gamma <= 0 || len(h.Beta) != m.GlobalK+1 {
+		return fmt.Errorf("model: hdp does not match %d global topics", m.GlobalK)
+	}
+	return nil
+}
+
+// globalAlpha returns the prior weight of global topic z in document d.
+func (m *MGLDA) globalAlpha(d, z int) float64 {
+	if m.HDP != nil {
+		return m.HDP.Alpha * m.HDP.Beta[z]
+	}
+	if m.Covariates != nil {
+		return m.Covariates.prior(m, d)[z]
+	}
+	return m.GlobalAlpha
+}
+
+// globalAlphaSum returns the sum of the prior weights of the global topics
+// in document d, including the weight of new topics under an HDP.
+func (m *MGLDA) globalAlphaSum(d int) float64 {
+	if m.HDP != nil {
+		return m.HDP.Alpha
+	}
+	if m.Covariates != nil {
+		var sum float64
+		for _, a := range m.Covariates.prior(m, d) {
+			sum += a
+		}
+		return sum
+	}
+	return float64(m.GlobalK) * m.GlobalAlpha
+}
+
+// addGlobalTopic opens a new, empty global topic, which takes a share of
+// the weight of the unused topics drawn from Beta(1, Gamma).
+func (m *MGLDA) addGlobalTopic() {
+	var err error
+	if m.Nglzw, err = m.Nglzw.Stack(matrix.Zeros(1, m.W)); err != nil {
+		panic(err)
+	}
+	if m.Nglz, err = m.Nglz.Stack(matrix.Zeros(1, 1)); err != nil {
+		panic(err)
+	}
+	if m.Ndglz, err = m.Ndglz.Augment(matrix.Zeros(m.Ndglz.Rows(), 1)); err != nil {
+		panic(err)
+	}
+	if p := m.globalSeeds; p != nil {
+		p.words = append(p.words, map[int]float64{})
+		p.sum = append(p.sum, float64(m.W)*m.GlobalBeta)
+	}
+
+	h := m.HDP
+	u := h.Beta[m.GlobalK]
+	b := 1 - math.Pow(m.random().Float64(), 1/h.Gamma)
+	h.Beta = append(h.Beta[:m.GlobalK], b*u, (1-b)*u)
+	m.GlobalK++
+}
+
+// removeGlobalTopics removes the global topics without words, but keeps at
+// least one topic. Their weight returns to the unused topics, and labels and
+// assignments of uncounted documents are moved along.
+func (m *MGLDA) removeGlobalTopics() {
+	keep := []int{}
+	newID := make([]int, m.GlobalK)
+	for z := 0; z < m.GlobalK; z++ {
+		newID[z] = -1
+		if m.Nglz.Get(z, 0) > 0 || (z == m.GlobalK-1 && len(keep) == 0) {
+			newID[z] = len(keep)
+			keep = append(keep, z)
+		}
+	}
+	if len(keep) == m.GlobalK {
+		return
+	}
+
+	nglzw := matrix.Zeros(len(keep), m.W)
+	nglz := matrix.Zeros(len(keep), 1)
+	ndglz := matrix.Zeros(m.Ndglz.Rows(), len(keep))
+	h := m.HDP
+	beta := make([]float64, len(keep)+1)
+	beta[len(keep)] = h.Beta[m.GlobalK]
+	for z := 0; z < m.GlobalK; z++ {
+		if newID[z] < 0 {
+			beta[len(keep)] += h.Beta[z]
+		}
+	}
+	for i, z := range keep {
+		for w := 0; w < m.W; w++ {
+			nglzw.Set(i, w, m.Nglzw.Get(z, w))
+		}
+		nglz.Set(i, 0, m.Nglz.Get(z, 0))
+		for d := 0; d < m.Ndglz.Rows(); d++ {
+			ndglz.Set(d, i, m.Ndglz.Get(d, z))
+		}
+		beta[i] = h.Beta[z]
+	}
+	m.Nglzw, m.Nglz, m.Ndglz, h.Beta = nglzw, nglz, ndglz, beta
+
+	for d := range m.Zdsn {
+		for s := range m.Zdsn[d] {
+			for w, z := range m.Zdsn[d][s] {
+				if m.Rdsn[d][s][w] != globalTopic {
+					continue
+				}
+				// only words of uncounted documents can be in a removed topic
+				if newID[z] < 0 {
+					m.Zdsn[d][s][w] = 0
+				} else {
+					m.Zdsn[d][s][w] = newID[z]
+				}
+			}
+		}
+	}
+	if p := m.globalSeeds; p != nil {
+		words, sum := []map[int]float64{}, []float64{}
+		for _, z := range keep {
+			words, sum = append(words, p.words[z]), append(sum, p.sum[z])
+		}
+		p.words, p.sum = words, sum
+	}
+	labels := TopicLabels{}
+	for _, label := range m.Labels {
+		if label.Kind == Global {
+			if newID[label.Topic] < 0 {
+				continue
+			}
+			label.Topic = newID[label.Topic]
+		}
+		labels = append(labels, label)
+	}
+	m.Labels = labels
+	m.GlobalK = len(keep)
+}
+
+// sampleBeta resamples the top-level weights from the number of tables of
+// every global topic, drawn given the counts of the documents.
+func (m *MGLDA) sampleBeta() {
+	h := m.HDP
+	r := m.random()
+	params := make([]float64, m.GlobalK+1)
+	for z := 0; z < m.GlobalK; z++ {
+		ab := h.Alpha * h.Beta[z]
+		for d := 0; d < m.Ndglz.Rows(); d++ {
+			n := int(m.Ndglz.Get(d, z))
+			for i := 0; i < n; i++ {
+				if r.Float64() < ab/(ab+float64(i)) {
+					params[z]++
+				}
+			}
+		}
+	}
+	params[m.GlobalK] = h.Gamma
+	h.Beta = sampleDirichlet(r, params)
+}
+
+// updateHDP removes empty global topics, resamples the top-level weights and
+// records the number of global topics after a sweep.
+func (m *MGLDA) updateHDP() {
+	m.removeGlobalTopics()
+	m.sampleBeta()
+	m.HDP.Topics = append(m.HDP.Topics, m.GlobalK)
+	glog.Infof("hdp: %d global topics", m.GlobalK)
+}
+
+// sampleGamma draws from Gamma(shape, 1) (Marsaglia and Tsang, 2000).
+func sampleGamma(r source, shape float64) float64 {
+	if shape <= 0 {
+		return 0
+	}
+	if shape < 1 {
+		return sampleGamma(r, shape+1) * math.Pow(r.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := r.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := r.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
+
+// sampleDirichlet draws from Dirichlet(params); zero parameters get zero
+// weight.
+func sampleDirichlet(r source, params []float64) []float64 {
+	x := make([]float64, len(params))
+	for i, a := range params {
+		x[i] = sampleGamma(r, a)
+	}
+	return normalizeRow(x)
+}
@@ -0,0 +1,58 @@
+package mglda
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHDP(t *testing.T) {
+	d := reviews(20)
+	m := NewMGLDA(1, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d)
+	assert.NotNil(t, m.EnableHDP(0, 1))
+	assert.Nil(t, m.EnableHDP(5, 5))
+	assert.Equal(t, []float64{0.5, 0.5}, m.HDP.Beta)
+
+	n := float64(20 * 15)
+	topics := map[int]bool{}
+	for i := 0; i < 30; i++ {
+		m.Inference()
+		topics[m.GlobalK] = true
+
+		assert.Equal(t, m.GlobalK+1, len(m.HDP.Beta))
+		assert.InDelta(t, 1, sum(m.HDP.Beta), 1e-9)
+		assert.Equal(t, m.GlobalK, m.Nglzw.Rows())
+		assert.Equal(t, m.GlobalK, m.Ndglz.Cols())
+		var total float64
+		for z := 0; z < m.GlobalK; z++ {
+			assert.True(t, m.Nglz.Get(z, 0) > 0)
+			total += m.Nglz.Get(z, 0)
+		}
+		assert.InDelta(t, n, total+m.Nlocz.Get(0, 0)+m.Nlocz.Get(1, 0), 1e-9)
+	}
+	assert.Equal(t, 30, len(m.HDP.Topics))
+	assert.True(t, len(topics) > 1)
+
+	// the counts of the assignments agree with the model
+	rebuilt := newMGLDA(m.GlobalK, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, m.Docs)
+	for dd := range *m.Docs {
+		rebuilt.allocDocument(dd)
+	}
+	rebuilt.Vdsn, rebuilt.Rdsn, rebuilt.Zdsn = m.Vdsn, m.Rdsn, m.Zdsn
+	for dd := range *m.Docs {
+		rebuilt.loadDocument(dd)
+	}
+	assert.Equal(t, rebuilt.Nglzw.Array(), m.Nglzw.Array())
+	assert.Equal(t, rebuilt.Ndglz.Array(), m.Ndglz.Array())
+
+	gl, _ := m.DocTopicDist(0)
+	assert.InDelta(t, 1-m.HDP.Alpha*m.HDP.Beta[m.GlobalK]/(m.Ndgl.Get(0, 0)+m.HDP.Alpha), sum(gl), 1e-9)
+
+	var buf bytes.Buffer
+	assert.Nil(t, SaveModel(&buf, m, nil))
+	loaded, _, err := LoadModel(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, m.HDP, loaded.HDP)
+	assert.Equal(t, m.GlobalK, loaded.GlobalK)
+}
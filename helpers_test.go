@@ -0,0 +1,40 @@
+package mglda
+
+import "math/rand"
+
+// reviews returns documents where word 0 marks a good and word 1 a bad
+// review of the single rated aspect, among neutral words 2..5. The neutral
+// words are the same for every call.
+func reviews(n int) []Document {
+	r := rand.New(rand.NewSource(1))
+	result := []Document{}
+	for i := 0; i < n; i++ {
+		good := i%2 == 0
+		doc := Document{Ratings: []int{1}}
+		if good {
+			doc.Ratings[0] = 5
+		}
+		for s := 0; s < 3; s++ {
+			sent := Sentense{}
+			for w := 0; w < 4; w++ {
+				sent.Words = append(sent.Words, 2+r.Intn(4))
+			}
+			if good {
+				sent.Words = append(sent.Words, 0)
+			} else {
+				sent.Words = append(sent.Words, 1)
+			}
+			doc.Sentenses = append(doc.Sentenses, sent)
+		}
+		result = append(result, doc)
+	}
+	return result
+}
+
+func sum(x []float64) float64 {
+	var s float64
+	for _, v := range x {
+		s += v
+	}
+	return s
+}
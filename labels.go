@@ -0,0 +1,132 @@
+package mglda
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TopicLabel names one topic.
+type TopicLabel struct {
+	Kind        TopicKind `json:"kind"`
+	Topic       int       `json:"topic"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+}
+
+// TopicLabels holds the labels of the topics of a model. Topics without a
+// label are shown by kind and id only.
+type TopicLabels []TopicLabel
+
+// Label returns the label of topic z of the given kind.
+func (l TopicLabels) Label(kind TopicKind, z int) (TopicLabel, bool) {
+	for _, label := range l {
+		if label.Kind == kind && label.Topic == z {
+			return label, true
+		}
+	}
+	return TopicLabel{}, false
+}
+
+// Name returns the name of topic z of the given kind, or "" if it has no
+// label.
+func (l TopicLabels) Name(kind TopicKind, z int) string {
+	label, _ := l.Label(kind, z)
+	return label.Name
+}
+
+// Description returns the description of topic z of the given kind.
+func (l TopicLabels) Description(kind TopicKind, z int) string {
+	label, _ := l.Label(kind, z)
+	return label.Description
+}
+
+// nameSuffix formats a topic name for the text outputs.
+func nameSuffix(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [%s]", name)
+}
+
+// ReadTopicLabels reads a json array of labels.
+func ReadTopicLabels(r io.Reader) (TopicLabels, error) {
+	labels := TopicLabels{}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&labels); err != nil {
+		return nil, fmt.Errorf("labels: %v", err)
+	}
+	return labels, nil
+}
+
+// WriteTopicLabels writes labels as a json array.
+func WriteTopicLabels(w io.Writer, labels TopicLabels) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(labels)
+}
+
+// SetLabels attaches labels to m. Every label must name an existing topic,
+// at most once.
+func (m *MGLDA) SetLabels(labels TopicLabels) error {
+	seen := map[TopicKind]map[int]bool{Global: {}, Local: {}}
+	for _, label := range labels {
+		k := m.GlobalK
+		switch label.Kind {
+		case Global:
+		case Local:
+			k = m.LocalK
+		default:
+			return fmt.Errorf("labels: invalid topic kind %q", label.Kind)
+		}
+		if label.Topic < 0 || label.Topic >= k {
+			return fmt.Errorf("labels: %s topic %d out of range", label.Kind, label.Topic)
+		}
+		if seen[label.Kind][label.Topic] {
+			return fmt.Errorf("labels: %s topic %d is labelled twice", label.Kind, label.Topic)
+		}
+		seen[label.Kind][label.Topic] = true
+	}
+	m.Labels = labels
+	return nil
+}
+
+// SuggestLabels suggests a label for every topic from its top words ranked
+// by opt: the name joins the first n of them with "_" and the description
+// lists all of them. vocabulary may be nil.
+func (m *MGLDA) SuggestLabels(vocabulary []string, n int, opt TopWordsOptions) TopicLabels {
+	labels := TopicLabels{}
+	for _, kind := range []TopicKind{Global, Local} {
+		for z, words := range m.TopWords(kind, vocabulary, opt) {
+			all := make([]string, len(words))
+			for i, word := range words {
+				all[i] = word.Label
+			}
+			name := all
+			if len(name) > n {
+				name = name[:n]
+			}
+			labels = append(labels, TopicLabel{
+				Kind:        kind,
+				Topic:       z,
+				Name:        strings.Join(name, "_"),
+				Description: strings.Join(all, ", "),
+			})
+		}
+	}
+	return labels
+}
+
+// Merge returns l with the labels of other added for every topic that l
+// does not label.
+func (l TopicLabels) Merge(other TopicLabels) TopicLabels {
+	merged := append(TopicLabels{}, l...)
+	for _, label := range other {
+		if _, ok := l.Label(label.Kind, label.Topic); !ok {
+			merged = append(merged, label)
+		}
+	}
+	return merged
+}
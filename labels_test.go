@@ -0,0 +1,66 @@
+package mglda
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicLabels(t *testing.T) {
+	labels, err := ReadTopicLabels(strings.NewReader(`[
+		{"kind": "local", "topic": 1, "name": "mail", "description": "emails"},
+		{"kind": "global", "topic": 0, "name": "money"}]`))
+	assert.Nil(t, err)
+	assert.Equal(t, "mail", labels.Name(Local, 1))
+	assert.Equal(t, "emails", labels.Description(Local, 1))
+	assert.Equal(t, "", labels.Name(Local, 0))
+	assert.Equal(t, "", TopicLabels(nil).Name(Global, 0))
+
+	merged := labels.Merge(TopicLabels{{Kind: Local, Topic: 1, Name: "other"}, {Kind: Local, Topic: 0, Name: "new"}})
+	assert.Equal(t, 3, len(merged))
+	assert.Equal(t, "mail", merged.Name(Local, 1))
+	assert.Equal(t, "new", merged.Name(Local, 0))
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteTopicLabels(&buf, labels))
+	read, err := ReadTopicLabels(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, labels, read)
+}
+
+func TestSetLabels(t *testing.T) {
+	d := append([]Document{}, docs...)
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &d)
+	m.Train(2)
+	assert.NotNil(t, m.SetLabels(TopicLabels{{Kind: Local, Topic: 2}}))
+	assert.NotNil(t, m.SetLabels(TopicLabels{{Kind: "other"}}))
+	assert.NotNil(t, m.SetLabels(TopicLabels{{Kind: Global}, {Kind: Global}}))
+
+	suggested := m.SuggestLabels(vocabulary, 2, DefaultTopWordsOptions())
+	assert.Equal(t, 6, len(suggested))
+	top := m.TopWords(Local, vocabulary, DefaultTopWordsOptions())
+	assert.Equal(t, top[1][0].Label+"_"+top[1][1].Label, suggested.Name(Local, 1))
+
+	labels := TopicLabels{{Kind: Local, Topic: 1, Name: "mail"}}.Merge(suggested)
+	assert.Nil(t, m.SetLabels(labels))
+	assert.Equal(t, "mail", m.Topics(vocabulary, DefaultTopWordsOptions())[5].Name)
+	assert.Equal(t, "mail", m.Coherence(5, nil, DocumentUnit)[5].Name)
+	assert.Equal(t, "mail", m.Diagnostics(DefaultDiagnosticsOptions()).Topics[5].Name)
+	assert.Equal(t, "local:1 mail", m.LDAvis(vocabulary, Global, Local).TopicLabels[5])
+
+	var buf bytes.Buffer
+	wt := bufio.NewWriter(&buf)
+	WriteTopics(m, vocabulary, DefaultTopWordsOptions(), wt)
+	wt.Flush()
+	assert.Contains(t, buf.String(), "-- local topic: 1 [mail] (")
+
+	buf.Reset()
+	assert.Nil(t, SaveModel(&buf, m, vocabulary))
+	loaded, _, err := LoadModel(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, m.Labels, loaded.Labels)
+}
@@ -0,0 +1,121 @@
+package mglda
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// LDAvisData holds the inputs of LDAvis (and pyLDAvis.prepare).
+// TopicLabels is not an input and is left out of the JSON; it names the
+// topics in the order of the rows of TopicTermDists, followed by the label
+// name of the topic, and is written by WriteLDAvisLabels.
+type LDAvisData struct {
+	TopicTermDists [][]float64 `json:"topic_term_dists"`
+	DocTopicDists  [][]float64 `json:"doc_topic_dists"`
+	DocLengths     []int       `json:"doc_lengths"`
+	Vocab          []string    `json:"vocab"`
+	TermFrequency  []int       `json:"term_frequency"`
+	TopicLabels    []string    `json:"-"`
+}
+
+// LDAvis computes the LDAvis inputs for the topics of the given kinds from
+// the count matrices. With both kinds the global topics come first and the
+// document distributions are weighted by the share of global and local
+// tokens in each document. Holdout documents are left out.
+func (m *MGLDA) LDAvis(vocabulary []string, kinds ...TopicKind) *LDAvisData {
+	data := &LDAvisData{
+		TopicTermDists: [][]float64{},
+		DocTopicDists:  [][]float64{},
+		DocLengths:     []int{},
+		Vocab:          make([]string, m.W),
+		TermFrequency:  make([]int, m.W),
+		TopicLabels:    []string{},
+	}
+	for w := 0; w < m.W; w++ {
+		data.Vocab[w] = wordLabel(vocabulary, w)
+		var n float64
+		for z := 0; z < m.GlobalK; z++ {
+			n += m.Nglzw.Get(z, w)
+		}
+		for z := 0; z < m.LocalK; z++ {
+			n += m.Nloczw.Get(z, w)
+		}
+		data.TermFrequency[w] = int(n)
+	}
+
+	global, local := false, false
+	for _, kind := range kinds {
+		global = global || kind == Global
+		local = local || kind == Local
+	}
+	addTopics := func(kind TopicKind) {
+		nzw, nz, k := m.Nglzw, m.Nglz, m.GlobalK
+		if kind == Local {
+			nzw, nz, k = m.Nloczw, m.Nlocz, m.LocalK
+		}
+		for z := 0; z < k; z++ {
+			row := make([]float64, m.W)
+			for w := 0; w < m.W; w++ {
+				row[w] = (nzw.Get(z, w) + m.beta(kind, z, w)) / (nz.Get(z, 0) + m.betaSum(kind, z))
+			}
+			data.TopicTermDists = append(data.TopicTermDists, row)
+			label := fmt.Sprintf("%d", z)
+			if global && local {
+				label = fmt.Sprintf("%s:%d", kind, z)
+			}
+			if name := m.Labels.Name(kind, z); name != "" {
+				label += " " + name
+			}
+			data.TopicLabels = append(data.TopicLabels, label)
+		}
+	}
+	if global {
+		addTopics(Global)
+	}
+	if local {
+		addTopics(Local)
+	}
+
+	for d, doc := range *m.Docs {
+		if doc.State == Holdout {
+			continue
+		}
+		thetaGl, thetaLoc := m.DocTopicDist(d)
+		row := []float64{}
+		switch {
+		case global && local:
+			n := float64(doc.NumberOfWords())
+			share := (m.Ndgl.Get(d, 0) + m.GlobalAlphaMix) /
+				(n + m.GlobalAlphaMix + m.LocalAlphaMix)
+			for _, p := range thetaGl {
+				row = append(row, share*p)
+			}
+			for _, p := range thetaLoc {
+				row = append(row, (1-share)*p)
+			}
+		case global:
+			row = thetaGl
+		case local:
+			row = thetaLoc
+		}
+		data.DocTopicDists = append(data.DocTopicDists, row)
+		data.DocLengths = append(data.DocLengths, doc.NumberOfWords())
+	}
+	return data
+}
+
+// WriteLDAvis writes data as JSON.
+func WriteLDAvis(w io.Writer, data *LDAvisData) error {
+	return json.NewEncoder(w).Encode(data)
+}
+
+// WriteLDAvisLabels writes the topic labels of data, one per line.
+func WriteLDAvisLabels(w io.Writer, data *LDAvisData) error {
+	for _, label := range data.TopicLabels {
+		if _, err := fmt.Fprintln(w, label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package mglda
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLDAvis(t *testing.T) {
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &docs)
+
+	data := m.LDAvis(vocabulary, Local)
+	assert.Equal(t, 2, len(data.TopicTermDists))
+	assert.Equal(t, []string{"0", "1"}, data.TopicLabels)
+
+	data = m.LDAvis(vocabulary, Global, Local)
+	assert.Equal(t, 6, len(data.TopicTermDists))
+	assert.Equal(t, "local:1", data.TopicLabels[5])
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteLDAvis(&buf, data))
+	assert.NotContains(t, buf.String(), "topic_labels")
+	buf.Reset()
+	assert.Nil(t, WriteLDAvisLabels(&buf, data))
+	assert.Equal(t, "global:0\nglobal:1\nglobal:2\nglobal:3\nlocal:0\nlocal:1\n", buf.String())
+	assert.Equal(t, vocabulary, data.Vocab)
+	assert.Equal(t, []int{docs[0].NumberOfWords()}, data.DocLengths)
+
+	freq := 0
+	for _, n := range data.TermFrequency {
+		freq += n
+	}
+	assert.Equal(t, docs[0].NumberOfWords(), freq)
+	for _, row := range append(data.TopicTermDists, data.DocTopicDists...) {
+		var sum float64
+		for _, p := range row {
+			sum += p
+		}
+		assert.InDelta(t, 1, sum, 1e-9)
+	}
+}
@@ -0,0 +1,300 @@
+package mglda
+
+import (
+	"fmt"
+	"math"
+)
+
+// RatingModel is the rating predictor of the Multi-Aspect Sentiment model
+// (Titov and McDonald, 2008). Local topic a < Aspects is tied to the rated
+// aspect a of Document.Ratings. The rating of aspect a is predicted by a
+// maximum entropy classifier over the words of the document, where the
+// words assigned to local topic a add aspect specific weights; during
+// sampling the rating likelihood pulls words that predict the observed
+// rating of an aspect into its local topic.
+type RatingModel struct {
+	Aspects int     `json:"aspects"`
+	Levels  int     `json:"levels"`
+	Rate    float64 `json:"rate"`
+	L2      float64 `json:"l2"`
+	// Bias is indexed by aspect and rating level.
+	Bias [][]float64 `json:"bias"`
+	// Common is indexed by rating level and word.
+	Common [][]float64 `json:"common"`
+	// Aspect is indexed by aspect, rating level and word.
+	Aspect [][][]float64 `json:"aspect"`
+
+	// scores caches the classifier scores of every rated document, indexed
+	// by document, aspect and rating level; nil for unrated documents.
+	scores [][][]float64
+}
+
+// EnableRatings attaches a rating predictor for aspects rated aspects with
+// ratings 1..levels to m. rate is the learning rate and l2 the L2 penalty
+// of the classifier, which is updated after every sweep of Train.
+func (m *MGLDA) EnableRatings(aspects, levels int, rate, l2 float64) error {
+	switch {
+	case aspects <= 0 || aspects > m.LocalK:
+		return fmt.Errorf("mas: %d aspects, want 1..%d (local_k)", aspects, m.LocalK)
+	case levels < 2:
+		return fmt.Errorf("mas: %d rating levels, want at least 2", levels)
+	}
+	if err := validateRatings(*m.Docs, aspects, levels); err != nil {
+		return err
+	}
+	rm := &RatingModel{
+		Aspects: aspects,
+		Levels:  levels,
+		Rate:    rate,
+		L2:      l2,
+		Bias:    zeros2(aspects, levels),
+		Common:  zeros2(levels, m.W),
+		Aspect:  make([][][]float64, aspects),
+	}
+	for a := range rm.Aspect {
+		rm.Aspect[a] = zeros2(levels, m.W)
+	}
+	m.Ratings = rm
+	rm.refresh(m)
+	return nil
+}
+
+func zeros2(rows, cols int) [][]float64 {
+	x := make([][]float64, rows)
+	for i := range x {
+		x[i] = make([]float64, cols)
+	}
+	return x
+}
+
+// validateRatings checks that every rating is 0 (not rated) or in
+// 1..levels, for at most aspects aspects.
+func validateRatings(docs []Document, aspects, levels int) error {
+	for d, doc := range docs {
+		if len(doc.Ratings) > aspects {
+			return fmt.Errorf("mas: document %d has %d ratings, want at most %d", d, len(doc.Ratings), aspects)
+		}
+		for a, y := range doc.Ratings {
+			if y < 0 || y > levels {
+				return fmt.Errorf("mas: document %d: rating %d of aspect %d out of range", d, y, a)
+			}
+		}
+	}
+	return nil
+}
+
+// rating returns the observed rating level of aspect a of document d as a
+// 0-based index, or -1 if it is not rated.
+func (rm *RatingModel) rating(m *MGLDA, d, a int) int {
+	ratings := (*m.Docs)[d].Ratings
+	if a >= len(ratings) || ratings[a] == 0 {
+		return -1
+	}
+	return ratings[a] - 1
+}
+
+func rated(doc Document) bool {
+	for _, y := range doc.Ratings {
+		if y > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// docScores computes the classifier scores of document d from scratch.
+func (rm *RatingModel) docScores(m *MGLDA, d int) [][]float64 {
+	scores := make([][]float64, rm.Aspects)
+	for a := range scores {
+		scores[a] = append([]float64{}, rm.Bias[a]...)
+	}
+	for s, sent := range (*m.Docs)[d].Sentenses {
+		for w, wd := range sent.Words {
+			for a := range scores {
+				for y := range scores[a] {
+					scores[a][y] += rm.Common[y][wd]
+				}
+			}
+			if z := m.Zdsn[d][s][w]; m.Rdsn[d][s][w] == localTopic && z < rm.Aspects {
+				for y := range scores[z] {
+					scores[z][y] += rm.Aspect[z][y][wd]
+				}
+			}
+		}
+	}
+	return scores
+}
+
+// refresh recomputes the cached scores of every rated document.
+func (rm *RatingModel) refresh(m *MGLDA) {
+	rm.scores = make([][][]float64, len(*m.Docs))
+	for d, doc := range *m.Docs {
+		if doc.State != Holdout && rated(doc) {
+			rm.scores[d] = rm.docScores(m, d)
+		}
+	}
+}
+
+// move updates the cached scores of document d when word wd leaves (sign
+// -1) or joins (sign 1) local topic z.
+func (rm *RatingModel) move(d int, r string, z, wd int, sign float64) {
+	if r != localTopic || z >= rm.Aspects || d >= len(rm.scores) || rm.scores[d] == nil {
+		return
+	}
+	for y := range rm.scores[d][z] {
+		rm.scores[d][z][y] += sign * rm.Aspect[z][y][wd]
+	}
+}
+
+// factor is the ratio of the likelihood of the observed rating of aspect z
+// of document d with word wd assigned to local topic z over that without.
+func (rm *RatingModel) factor(m *MGLDA, d, z, wd int) float64 {
+	if z >= rm.Aspects || d >= len(rm.scores) || rm.scores[d] == nil {
+		return 1
+	}
+	y := rm.rating(m, d, z)
+	if y < 0 {
+		return 1
+	}
+	s := rm.scores[d][z]
+	with := make([]float64, len(s))
+	for i := range s {
+		with[i] = s[i] + rm.Aspect[z][i][wd]
+	}
+	return math.Exp(with[y] - logSumExp(with) - s[y] + logSumExp(s))
+}
+
+func logSumExp(x []float64) float64 {
+	max := math.Inf(-1)
+	for _, v := range x {
+		max = math.Max(max, v)
+	}
+	var sum float64
+	for _, v := range x {
+		sum += math.Exp(v - max)
+	}
+	return max + math.Log(sum)
+}
+
+func softmax(x []float64) []float64 {
+	lse := logSumExp(x)
+	p := make([]float64, len(x))
+	for i, v := range x {
+		p[i] = math.Exp(v - lse)
+	}
+	return p
+}
+
+// update runs one epoch of gradient ascent on the L2 penalized
+// log-likelihood of the observed ratings given the current assignments.
+func (rm *RatingModel) update(m *MGLDA) {
+	for d, doc := range *m.Docs {
+		if doc.State != Active || !rated(doc) {
+			continue
+		}
+		counts := map[int]float64{}
+		aspectCounts := make([]map[int]float64, rm.Aspects)
+		for a := range aspectCounts {
+			aspectCounts[a] = map[int]float64{}
+		}
+		for s, sent := range doc.Sentenses {
+			for w, wd := range sent.Words {
+				counts[wd]++
+				if z := m.Zdsn[d][s][w]; m.Rdsn[d][s][w] == localTopic && z < rm.Aspects {
+					aspectCounts[z][wd]++
+				}
+			}
+		}
+
+		scores := rm.docScores(m, d)
+		for a := 0; a < rm.Aspects; a++ {
+			target := rm.rating(m, d, a)
+			if target < 0 {
+				continue
+			}
+			p := softmax(scores[a])
+			for y := range p {
+				g := -p[y]
+				if y == target {
+					g++
+				}
+				rm.Bias[a][y] += rm.Rate * g
+				for wd, n := range counts {
+					rm.Common[y][wd] += rm.Rate * (g*n - rm.L2*rm.Common[y][wd])
+				}
+				for wd, n := range aspectCounts[a] {
+					rm.Aspect[a][y][wd] += rm.Rate * (g*n - rm.L2*rm.Aspect[a][y][wd])
+				}
+			}
+		}
+	}
+	rm.refresh(m)
+}
+
+// RatingDist returns the predicted distribution over rating levels 1..Levels
+// of every aspect of document d.
+func (m *MGLDA) RatingDist(d int) [][]float64 {
+	rm := m.Ratings
+	scores := rm.docScores(m, d)
+	dist := make([][]float64, rm.Aspects)
+	for a := range scores {
+		dist[a] = softmax(scores[a])
+	}
+	return dist
+}
+
+// ExpectedRatings returns the expected rating of every aspect of document d.
+func (m *MGLDA) ExpectedRatings(d int) []float64 {
+	dist := m.RatingDist(d)
+	ratings := make([]float64, len(dist))
+	for a, p := range dist {
+		for y := range p {
+			ratings[a] += float64(y+1) * p[y]
+		}
+	}
+	return ratings
+}
+
+// PredictRatings infers the assignments of new reviews without ratings (see
+// Infer) and returns the expected rating of every aspect of each of them.
+// Any ratings of docs are ignored. m must have a rating model.
+func (m *MGLDA) PredictRatings(docs []Document, iteration int) ([][]float64, error) {
+	if m.Ratings == nil {
+		return nil, fmt.Errorf("mas: model has no rating model")
+	}
+	unrated := make([]Document, len(docs))
+	for i, doc := range docs {
+		u := doc
+		u.Ratings = nil
+		unrated[i] = u
+	}
+	first := m.Infer(unrated, iteration)
+	ratings := make([][]float64, len(docs))
+	for i := range docs {
+		ratings[i] = m.ExpectedRatings(first + i)
+	}
+	return ratings, nil
+}
+
+// checkRatingModel verifies that a loaded rating model matches the shape
+// of m and the ratings of its documents.
+func checkRatingModel(m *MGLDA, rm *RatingModel) error {
+	if rm.Aspects <= 0 || rm.Aspects > m.LocalK || rm.Levels < 2 {
+		return fmt.Errorf("model: invalid rating model with %d aspects and %d levels", rm.Aspects, rm.Levels)
+	}
+	ok := len(rm.Bias) == rm.Aspects && len(rm.Common) == rm.Levels && len(rm.Aspect) == rm.Aspects
+	for a := 0; ok && a < rm.Aspects; a++ {
+		ok = len(rm.Bias[a]) == rm.Levels && len(rm.Aspect[a]) == rm.Levels
+		for y := 0; ok && y < rm.Levels; y++ {
+			ok = len(rm.Aspect[a][y]) == m.W
+		}
+	}
+	for y := 0; ok && y < rm.Levels; y++ {
+		ok = len(rm.Common[y]) == m.W
+	}
+	if !ok {
+		return fmt.Errorf("model: rating weights do not match %d aspects, %d levels and %d words",
+			rm.Aspects, rm.Levels, m.W)
+	}
+	return validateRatings(*m.Docs, rm.Aspects, rm.Levels)
+}
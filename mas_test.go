@@ -0,0 +1,61 @@
+package mglda
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnableRatings(t *testing.T) {
+	d := reviews(4)
+	m := NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d)
+	assert.NotNil(t, m.EnableRatings(3, 5, 0.1, 0.01))
+	assert.NotNil(t, m.EnableRatings(1, 1, 0.1, 0.01))
+	assert.NotNil(t, m.EnableRatings(1, 4, 0.1, 0.01))
+	assert.Nil(t, m.EnableRatings(1, 5, 0.1, 0.01))
+}
+
+func TestPredictRatings(t *testing.T) {
+	d := reviews(20)
+	m := NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d)
+	assert.Nil(t, m.EnableRatings(1, 5, 0.05, 0.01))
+	m.Train(20)
+
+	// the cached scores follow the assignments
+	for dd := range d {
+		assert.InDeltaSlice(t, m.Ratings.docScores(m, dd)[0], m.Ratings.scores[dd][0], 1e-9)
+	}
+
+	plain := NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &[]Document{})
+	_, err := plain.PredictRatings(reviews(1), 1)
+	assert.NotNil(t, err)
+
+	test := reviews(2)
+	ratings, err := m.PredictRatings(test, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ratings))
+	assert.Equal(t, 1, len(ratings[0]))
+	assert.True(t, ratings[0][0] > 3)
+	assert.True(t, ratings[1][0] < 3)
+
+	// the window size of a document is kept
+	short := reviews(1)
+	short[0].Window = 1
+	first := len(*m.Docs)
+	_, err = m.PredictRatings(short, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, (*m.Docs)[first].Window)
+	for s := range m.Vdsn[first] {
+		for _, v := range m.Vdsn[first][s] {
+			assert.Equal(t, 0, v)
+		}
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, SaveModel(&buf, m, nil))
+	loaded, _, err := LoadModel(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, m.Ratings.Common, loaded.Ratings.Common)
+	assert.InDeltaSlice(t, m.ExpectedRatings(0), loaded.ExpectedRatings(0), 1e-9)
+}
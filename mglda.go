@@ -4,10 +4,10 @@ import (
 	"bufio"
 	"fmt"
 	"github.com/golang/glog"
-	"github.com/gonum/floats"
 	"github.com/skelterjohn/go.matrix"
 	"math"
 	"math/rand"
+	"time"
 )
 
 const (
@@ -16,6 +16,14 @@ const (
 	localTopic  = "loc"
 )
 
+// TopicKind tells global and local topics apart in reports.
+type TopicKind string
+
+const (
+	Global TopicKind = "global"
+	Local  TopicKind = "local"
+)
+
 type DocumentState uint
 
 const (
@@ -27,6 +35,17 @@ const (
 type Document struct {
 	Sentenses []Sentense `json:"sentenses"`
 	State     DocumentState
+	// Ratings holds the star rating (1..levels) of each rated aspect of a
+	// review, 0 if the aspect is not rated. See RatingModel.
+	Ratings []int `json:"ratings,omitempty"`
+	// Window is the number of sentences in a window of the document, at
+	// most the T of the model; 0 means T.
+	Window int `json:"window,omitempty"`
+	// Time is when the document was written, if known; see TopicTrends.
+	Time *time.Time `json:"time,omitempty"`
+	// Meta holds arbitrary metadata of the document, such as a product id
+	// or category; see AggregateByMeta and Covariates.
+	Meta map[string]interface{} `json:"meta,omitempty"`
 }
 
 type Sentense struct {
@@ -70,6 +89,33 @@ type MGLDA struct {
 	Nlocz          *matrix.DenseMatrix
 	Ndvloc         [][]float64
 	Ndvlocz        [][][]float64
+	// Trace holds the joint log-likelihood after each sweep run by Train.
+	Trace []float64
+	// Ratings is the optional rating predictor of the MAS extension.
+	Ratings *RatingModel
+	// HDP makes the number of global topics nonparametric if set.
+	HDP *HDP
+	// Covariates makes the global topic prior depend on the metadata of a
+	// document if set.
+	Covariates *Covariates
+	// Windows learns the window size of every document if set.
+	Windows *WindowPrior
+	// WindowGamma replaces Gamma by a prior weight for every window of a
+	// sentence if set, and is fitted after every sweep with LearnGamma; see
+	// SetWindowGamma.
+	WindowGamma []float64
+	LearnGamma  bool
+	// Labels names the topics in every output.
+	Labels TopicLabels
+	// Seeds are the seed words with a raised topic word prior; see SetSeeds.
+	Seeds       []SeedWord
+	globalSeeds *seedPrior
+	localSeeds  *seedPrior
+	// rng is the random source of the model if seeded; see SetSeed.
+	rng *rand.Rand
+	// fixed keeps the topics and priors as they are while documents are
+	// inferred; only their assignments and window sizes are sampled.
+	fixed bool
 }
 
 func (m *MGLDA) LogLikelihood() float64 {
@@ -79,7 +125,7 @@ func (m *MGLDA) LogLikelihood() float64 {
 		for j := 0; j < m.W; j++ {
 			Nzw := int(m.Nglzw.Get(i, j))
 			for n := 0; n < Nzw; n++ {
-				ll += math.Log((float64(n) + m.GlobalBeta) / (float64(ss) + float64(m.W)*m.GlobalBeta))
+				ll += math.Log((float64(n) + m.beta(Global, i, j)) / (float64(ss) + m.betaSum(Global, i)))
 				ss++
 			}
 		}
@@ -90,7 +136,7 @@ func (m *MGLDA) LogLikelihood() float64 {
 		for j := 0; j < m.W; j++ {
 			Nzw := int(m.Nloczw.Get(i, j))
 			for n := 0; n < Nzw; n++ {
-				ll += math.Log((float64(n) + m.LocalBeta) / (float64(ss) + float64(m.W)*m.LocalBeta))
+				ll += math.Log((float64(n) + m.beta(Local, i, j)) / (float64(ss) + m.betaSum(Local, i)))
 				ss++
 			}
 		}
@@ -99,6 +145,13 @@ func (m *MGLDA) LogLikelihood() float64 {
 	return ll
 }
 
+// JointLogLikelihood returns the log probability of the words and of the
+// window assignments of the counted documents, LogLikelihood plus
+// WindowLogLikelihood, so that it reflects the window prior.
+func (m *MGLDA) JointLogLikelihood() float64 {
+	return m.LogLikelihood() + m.WindowLogLikelihood()
+}
+
 // Inference runs a go routine for each doc.
 func (m *MGLDA) Inference() {
 	for d, doc := range *m.Docs {
@@ -111,85 +164,17 @@ func (m *MGLDA) Inference() {
 				r := m.Rdsn[d][s][w]
 				z := m.Zdsn[d][s][w]
 
-				if r == globalTopic {
-					m.Nglzw.Set(z, wd, m.Nglzw.Get(z, wd)-1)
-					m.Nglz.Set(z, 0, m.Nglz.Get(z, 0)-1)
-					m.Ndvgl[d][s+v] -= 1
-					m.Ndglz.Set(d, z, m.Ndglz.Get(d, z)-1)
-					m.Ndgl.Set(d, 0, m.Ndgl.Get(d, 0)-1)
-				} else {
-					m.Nloczw.Set(z, wd, m.Nloczw.Get(z, wd)-1)
-					m.Nlocz.Set(z, 0, m.Nlocz.Get(z, 0)-1)
-					m.Ndvloc[d][s+v] -= 1
-					m.Ndvlocz[d][s+v][z] -= 1
-				}
-				m.Ndsv[d][s][v] -= 1
-				m.Nds[d][s] -= 1
-				m.Ndv[d][s+v] -= 1
-
-				pvrz := []float64{}
-				newVs := []int{}
-				newRs := []string{}
-				newZs := []int{}
-				for vt := 0; vt < m.T; vt++ {
-					for zt := 0; zt < m.GlobalK; zt++ {
-						newVs = append(newVs, vt)
-						newRs = append(newRs, globalTopic)
-						newZs = append(newZs, zt)
-						term1 := (m.Nglzw.Get(zt, wd) + m.GlobalBeta) / (m.Nglz.Get(zt, 0) + float64(m.W)*m.GlobalBeta)
-						term2 := (m.Ndsv[d][s][vt] + m.Gamma) / (m.Nds[d][s] + float64(m.T)*m.Gamma)
-						term3 := (m.Ndvgl[d][s+vt] + m.GlobalAlphaMix) / (m.Ndv[d][s+vt] + m.GlobalAlphaMix + m.LocalAlphaMix)
-						term4 := (m.Ndglz.Get(d, zt) + m.GlobalAlpha) / (m.Ndgl.Get(d, 0) + float64(m.GlobalK)*m.GlobalAlpha)
-						pvrz = append(pvrz, term1*term2*term3*term4)
-
-					}
-					for zt := 0; zt < m.LocalK; zt++ {
-						newVs = append(newVs, vt)
-						newRs = append(newRs, localTopic)
-						newZs = append(newZs, zt)
-						term1 := (m.Nloczw.Get(zt, wd) + m.LocalBeta) / (m.Nlocz.Get(zt, 0) + float64(m.W)*m.LocalBeta)
-						term2 := (m.Ndsv[d][s][vt] + m.Gamma) / (m.Nds[d][s] + float64(m.T)*m.Gamma)
-						term3 := (m.Ndvloc[d][s+vt] + m.LocalAlphaMix) / (m.Ndv[d][s+vt] + m.GlobalAlphaMix + m.LocalAlphaMix)
-						term4 := (m.Ndvlocz[d][s+vt][zt] + m.LocalAlpha) / (m.Ndvloc[d][s+vt] + float64(m.LocalK)*m.LocalAlpha)
-						pvrz = append(pvrz, term1*term2*term3*term4)
-					}
+				m.count(d, s, wd, v, r, z, -1)
+				if m.Ratings != nil {
+					m.Ratings.move(d, r, z, wd, -1)
 				}
 
-				// sampling from multinomial distribution
-				var randIdx int
-				var sum float64
-				for _, item := range pvrz {
-					sum += item
-				}
-
-				threshold := rand.Float64()
-				partialSum := 0.0
-				for i := 0; i < len(pvrz); i++ {
-					partialSum += pvrz[i] / sum
-					if partialSum >= threshold {
-						randIdx = i
-						break
-					}
-				}
-				newV := newVs[randIdx]
-				newR := newRs[randIdx]
-				newZ := newZs[randIdx]
+				newV, newR, newZ := m.sample(d, s, wd)
 				// update
-				if newR == globalTopic {
-					m.Nglzw.Set(newZ, wd, m.Nglzw.Get(newZ, wd)+1)
-					m.Nglz.Set(newZ, 0, m.Nglz.Get(newZ, 0)+1)
-					m.Ndvgl[d][s+newV] += 1
-					m.Ndglz.Set(d, newZ, m.Ndglz.Get(d, newZ)+1)
-					m.Ndgl.Set(d, 0, m.Ndgl.Get(d, 0)+1)
-				} else {
-					m.Nloczw.Set(newZ, wd, m.Nloczw.Get(newZ, wd)+1)
-					m.Nlocz.Set(newZ, 0, m.Nlocz.Get(newZ, 0)+1)
-					m.Ndvloc[d][s+newV] += 1
-					m.Ndvlocz[d][s+newV][newZ] += 1
+				m.count(d, s, wd, newV, newR, newZ, 1)
+				if m.Ratings != nil {
+					m.Ratings.move(d, newR, newZ, wd, 1)
 				}
-				m.Ndsv[d][s][newV] += 1
-				m.Nds[d][s] += 1
-				m.Ndv[d][s+newV] += 1
 
 				m.Vdsn[d][s][w] = newV
 				m.Rdsn[d][s][w] = newR
@@ -197,9 +182,167 @@ func (m *MGLDA) Inference() {
 			}
 		}
 	}
+	if m.HDP != nil && !m.fixed {
+		m.updateHDP()
+	}
+	if m.Windows != nil {
+		m.sampleWindows()
+	}
+	if m.LearnGamma && !m.fixed {
+		m.updateWindowGamma()
+	}
+}
+
+// count adds n to the counts of word wd of sentence s of document d
+// assigned to window v and topic z of kind r.
+func (m *MGLDA) count(d, s, wd, v int, r string, z int, n float64) {
+	if r == globalTopic {
+		m.Nglzw.Set(z, wd, m.Nglzw.Get(z, wd)+n)
+		m.Nglz.Set(z, 0, m.Nglz.Get(z, 0)+n)
+		m.Ndvgl[d][s+v] += n
+		m.Ndglz.Set(d, z, m.Ndglz.Get(d, z)+n)
+		m.Ndgl.Set(d, 0, m.Ndgl.Get(d, 0)+n)
+	} else {
+		m.Nloczw.Set(z, wd, m.Nloczw.Get(z, wd)+n)
+		m.Nlocz.Set(z, 0, m.Nlocz.Get(z, 0)+n)
+		m.Ndvloc[d][s+v] += n
+		m.Ndvlocz[d][s+v][z] += n
+	}
+	m.Ndsv[d][s][v] += n
+	m.Nds[d][s] += n
+	m.Ndv[d][s+v] += n
+}
+
+// conditional returns the unnormalized full conditional of word wd of
+// sentence s of document d given all other counts, over the windows and for
+// each window the global and then the local topics, followed under an HDP
+// by a new global topic in each window unless topics are fixed; see decode.
+func (m *MGLDA) conditional(d, s, wd int) []float64 {
+	t := m.window(d)
+	pvrz := make([]float64, 0, t*(m.GlobalK+m.LocalK))
+	for vt := 0; vt < t; vt++ {
+		for zt := 0; zt < m.GlobalK; zt++ {
+			term1 := (m.Nglzw.Get(zt, wd) + m.beta(Global, zt, wd)) / (m.Nglz.Get(zt, 0) + m.betaSum(Global, zt))
+			term2 := (m.Ndsv[d][s][vt] + m.gamma(vt)) / (m.Nds[d][s] + m.gammaSum(t))
+			term3 := (m.Ndvgl[d][s+vt] + m.GlobalAlphaMix) / (m.Ndv[d][s+vt] + m.GlobalAlphaMix + m.LocalAlphaMix)
+			term4 := (m.Ndglz.Get(d, zt) + m.globalAlpha(d, zt)) / (m.Ndgl.Get(d, 0) + m.globalAlphaSum(d))
+			pvrz = append(pvrz, term1*term2*term3*term4)
+
+		}
+		for zt := 0; zt < m.LocalK; zt++ {
+			term1 := (m.Nloczw.Get(zt, wd) + m.beta(Local, zt, wd)) / (m.Nlocz.Get(zt, 0) + m.betaSum(Local, zt))
+			term2 := (m.Ndsv[d][s][vt] + m.gamma(vt)) / (m.Nds[d][s] + m.gammaSum(t))
+			term3 := (m.Ndvloc[d][s+vt] + m.LocalAlphaMix) / (m.Ndv[d][s+vt] + m.GlobalAlphaMix + m.LocalAlphaMix)
+			term4 := (m.Ndvlocz[d][s+vt][zt] + m.LocalAlpha) / (m.Ndvloc[d][s+vt] + float64(m.LocalK)*m.LocalAlpha)
+			if m.Ratings != nil {
+				term4 *= m.Ratings.factor(m, d, zt, wd)
+			}
+			pvrz = append(pvrz, term1*term2*term3*term4)
+		}
+	}
+	if m.HDP != nil && !m.fixed {
+		// a new global topic in each window
+		for vt := 0; vt < t; vt++ {
+			term1 := 1 / float64(m.W)
+			term2 := (m.Ndsv[d][s][vt] + m.gamma(vt)) / (m.Nds[d][s] + m.gammaSum(t))
+			term3 := (m.Ndvgl[d][s+vt] + m.GlobalAlphaMix) / (m.Ndv[d][s+vt] + m.GlobalAlphaMix + m.LocalAlphaMix)
+			term4 := m.globalAlpha(d, m.GlobalK) / (m.Ndgl.Get(d, 0) + m.globalAlphaSum(d))
+			pvrz = append(pvrz, term1*term2*term3*term4)
+		}
+	}
+	return pvrz
+}
+
+// decode returns the window and topic of index i of a conditional of
+// document d. A new global topic has id GlobalK.
+func (m *MGLDA) decode(d, i int) (int, string, int) {
+	k := m.GlobalK + m.LocalK
+	if t := m.window(d); i >= t*k {
+		return i - t*k, globalTopic, m.GlobalK
+	}
+	if z := i % k; z < m.GlobalK {
+		return i / k, globalTopic, z
+	}
+	return i / k, localTopic, i%k - m.GlobalK
+}
+
+// sample draws a window and topic for word wd of sentence s of document d
+// from its full conditional given all other counts. Under an HDP a drawn
+// new global topic is opened.
+func (m *MGLDA) sample(d, s, wd int) (int, string, int) {
+	pvrz := m.conditional(d, s, wd)
+
+	// sampling from multinomial distribution
+	var randIdx int
+	var sum float64
+	for _, item := range pvrz {
+		sum += item
+	}
+
+	threshold := m.random().Float64()
+	partialSum := 0.0
+	for i := 0; i < len(pvrz); i++ {
+		partialSum += pvrz[i] / sum
+		if partialSum >= threshold {
+			randIdx = i
+			break
+		}
+	}
+	v, r, z := m.decode(d, randIdx)
+	if r == globalTopic && z == m.GlobalK {
+		m.addGlobalTopic()
+	}
+	return v, r, z
+}
+
+// source is a source of random numbers.
+type source interface {
+	Float64() float64
+	Intn(n int) int
+	NormFloat64() float64
+}
+
+// globalSource draws from the default source of math/rand.
+type globalSource struct{}
+
+func (globalSource) Float64() float64     { return rand.Float64() }
+func (globalSource) Intn(n int) int       { return rand.Intn(n) }
+func (globalSource) NormFloat64() float64 { return rand.NormFloat64() }
+
+// SetSeed gives m a random source of its own seeded by seed, so that
+// several models can be sampled concurrently and reproducibly. Otherwise
+// the default source of math/rand is used.
+func (m *MGLDA) SetSeed(seed int64) {
+	m.rng = rand.New(rand.NewSource(seed))
+}
+
+// random returns the random source of m.
+func (m *MGLDA) random() source {
+	if m.rng != nil {
+		return m.rng
+	}
+	return globalSource{}
+}
+
+// Train runs iteration sweeps of Inference and records the joint
+// log-likelihood after each of them in Trace. The rating predictor of a
+// rating model and the coefficients of covariates are updated after every
+// sweep.
+func (m *MGLDA) Train(iteration int) {
+	for i := 0; i < iteration; i++ {
+		m.Inference()
+		if m.Ratings != nil {
+			m.Ratings.update(m)
+		}
+		if m.Covariates != nil {
+			m.Covariates.update(m)
+		}
+		m.Trace = append(m.Trace, m.JointLogLikelihood())
+	}
 }
 
-// WordDist returns a topic word distribution
+// WordDist returns a topic word distribution. Seed words add the excess of
+// their prior over the symmetric one.
 func (m *MGLDA) WordDist() (*matrix.DenseMatrix, *matrix.DenseMatrix) {
 	newNglz := m.Nglz.Copy()
 	if err := newNglz.AddDense(matrix.Ones(newNglz.Rows(), newNglz.Cols())); err != nil {
@@ -212,23 +355,140 @@ func (m *MGLDA) WordDist() (*matrix.DenseMatrix, *matrix.DenseMatrix) {
 	}
 
 	newNglzw := m.Nglzw.Copy()
+	newNloczw := m.Nloczw.Copy()
+	// seed words keep the excess of their prior over the symmetric one
+	addSeeds := func(p *seedPrior, nzw, nz *matrix.DenseMatrix, beta float64) {
+		if p == nil {
+			return
+		}
+		for z, words := range p.words {
+			for w, b := range words {
+				nzw.Set(z, w, nzw.Get(z, w)+b-beta)
+			}
+			nz.Set(z, 0, nz.Get(z, 0)+p.sum[z]-float64(m.W)*beta)
+		}
+	}
+	addSeeds(m.globalSeeds, newNglzw, newNglz, m.GlobalBeta)
+	addSeeds(m.localSeeds, newNloczw, newNlocz, m.LocalBeta)
+
 	for i := 0; i < newNglzw.Rows(); i++ {
 		newNglzw.ScaleRow(i, float64(1)/newNglz.Get(i, 0))
 	}
 
-	newNloczw := m.Nloczw.Copy()
 	for i := 0; i < newNloczw.Rows(); i++ {
 		newNloczw.ScaleRow(i, float64(1)/newNlocz.Get(i, 0))
 	}
 	return newNglzw, newNloczw
 }
 
+// DocTopicDist returns the global topic distribution of document d and its
+// local topic distribution pooled over all windows of the document.
+func (m *MGLDA) DocTopicDist(d int) ([]float64, []float64) {
+	thetaGl := make([]float64, m.GlobalK)
+	for z := 0; z < m.GlobalK; z++ {
+		thetaGl[z] = (m.Ndglz.Get(d, z) + m.globalAlpha(d, z)) /
+			(m.Ndgl.Get(d, 0) + m.globalAlphaSum(d))
+	}
+
+	thetaLoc := make([]float64, m.LocalK)
+	var nloc float64
+	for v := range m.Ndvlocz[d] {
+		nloc += m.Ndvloc[d][v]
+		for z := 0; z < m.LocalK; z++ {
+			thetaLoc[z] += m.Ndvlocz[d][v][z]
+		}
+	}
+	for z := 0; z < m.LocalK; z++ {
+		thetaLoc[z] = (thetaLoc[z] + m.LocalAlpha) / (nloc + float64(m.LocalK)*m.LocalAlpha)
+	}
+	return thetaGl, thetaLoc
+}
+
+// SentenceLocalDist returns the local topic posterior of sentence s of
+// document d, mixing the local topic distributions of the windows covering
+// the sentence by the window distribution of the sentence.
+func (m *MGLDA) SentenceLocalDist(d, s int) []float64 {
+	dist := make([]float64, m.LocalK)
+	t := m.window(d)
+	for v := 0; v < t; v++ {
+		pv := (m.Ndsv[d][s][v] + m.gamma(v)) / (m.Nds[d][s] + m.gammaSum(t))
+		for z := 0; z < m.LocalK; z++ {
+			dist[z] += pv * (m.Ndvlocz[d][s+v][z] + m.LocalAlpha) /
+				(m.Ndvloc[d][s+v] + float64(m.LocalK)*m.LocalAlpha)
+		}
+	}
+	return dist
+}
+
+// Infer appends docs to the model and samples their assignments for
+// iteration sweeps while all other documents are frozen, so the topics
+// only move by the counts of docs themselves, and the number of topics and
+// the learned priors stay fixed. The inferred documents stay in the model as
+// frozen documents. It returns the index of the first of them.
+func (m *MGLDA) Infer(docs []Document, iteration int) int {
+	first := len(*m.Docs)
+	active := []int{}
+	for i := range *m.Docs {
+		if (*m.Docs)[i].State == Active {
+			(*m.Docs)[i].State = Frozen
+			active = append(active, i)
+		}
+	}
+
+	for _, doc := range docs {
+		doc.State = Active
+		*m.Docs = append(*m.Docs, doc)
+	}
+	m.growDocuments(len(*m.Docs))
+	for d := first; d < len(*m.Docs); d++ {
+		m.initDocument(d)
+		m.loadDocument(d)
+	}
+
+	m.fixed = true
+	for i := 0; i < iteration; i++ {
+		m.Inference()
+	}
+	m.fixed = false
+
+	for d := first; d < len(*m.Docs); d++ {
+		(*m.Docs)[d].State = Frozen
+	}
+	for _, d := range active {
+		(*m.Docs)[d].State = Active
+	}
+	return first
+}
+
 func NewMGLDA(globalK, localK int, gamma, globalAlpha, localAlpha,
+	globalAlphaMix, localAlphaMix, globalBeta, localBeta float64,
+	t, w int, docs *[]Document) *MGLDA {
+	m := newMGLDA(globalK, localK, gamma, globalAlpha, localAlpha,
+		globalAlphaMix, localAlphaMix, globalBeta, localBeta, t, w, docs)
+
+	glog.Info("random fitting MGLDA")
+	for d := range *docs {
+		m.initDocument(d)
+	}
+
+	glog.Info("initializing")
+	for d, doc := range *docs {
+		if doc.State == Holdout {
+			continue
+		}
+		m.loadDocument(d)
+	}
+
+	return m
+}
+
+// newMGLDA returns a model with empty counts and no per-document state.
+func newMGLDA(globalK, localK int, gamma, globalAlpha, localAlpha,
 	globalAlphaMix, localAlphaMix, globalBeta, localBeta float64,
 	t, w int, docs *[]Document) *MGLDA {
 	docLen := len(*docs)
 	inflation := float64(0)
-	m := MGLDA{
+	return &MGLDA{
 		GlobalK:        globalK,
 		LocalK:         localK,
 		Gamma:          gamma,
@@ -249,111 +509,89 @@ func NewMGLDA(globalK, localK int, gamma, globalAlpha, localAlpha,
 		Nloczw:         matrix.Zeros(localK, w),
 		Nlocz:          matrix.Zeros(localK, 1),
 	}
+}
 
-	glog.Info("random fitting MGLDA")
-	for _, doc := range *docs {
-		vd := [][]int{}
-		rd := [][]string{}
-		zd := [][]int{}
-		ndsvd := [][]float64{}
-		ndsd := []float64{}
-		initialValue := matrix.Numbers(len(doc.Sentenses)+t, 1, inflation).Array()
-		m.Ndvloc = append(m.Ndvloc, initialValue)
-		m.Ndvlocz = append(m.Ndvlocz, matrix.Numbers(len(doc.Sentenses)+t, localK, inflation).Arrays())
-		m.Ndv = append(m.Ndvloc, initialValue)
-		m.Ndvgl = append(m.Ndvgl, initialValue)
-
-		for _, sts := range doc.Sentenses {
-			vs := []int{}
-			rs := []string{}
-			zs := []int{}
-			for _ = range sts.Words {
-				vs = append(vs, rand.Intn(t))
-
-				tp := rand.Intn(2)
-				var r string
-				var z int
-				if tp == 0 {
-					r = globalTopic
-					z = rand.Intn(globalK)
-				} else {
-					r = localTopic
-					z = rand.Intn(localK)
-				}
-				rs = append(rs, r)
-				zs = append(zs, z)
-			}
-			vd = append(vd, vs)
-			rd = append(rd, rs)
-			zd = append(zd, zs)
+// growDocuments extends the document-indexed count matrices to n rows.
+func (m *MGLDA) growDocuments(n int) {
+	if n <= m.Ndglz.Rows() {
+		return
+	}
+	var err error
+	if m.Ndglz, err = m.Ndglz.Stack(matrix.Zeros(n-m.Ndglz.Rows(), m.GlobalK)); err != nil {
+		panic(err)
+	}
+	if m.Ndgl, err = m.Ndgl.Stack(matrix.Zeros(n-m.Ndgl.Rows(), 1)); err != nil {
+		panic(err)
+	}
+}
 
-			ndsvs := []float64{}
-			for i := 0; i < t; i++ {
-				ndsvs = append(ndsvs, inflation)
+// initDocument allocates the state of document d, which must be the next
+// document without state, and assigns its words to windows and topics
+// uniformly at random. The counts are not updated; see loadDocument.
+func (m *MGLDA) initDocument(d int) {
+	m.allocDocument(d)
+	r := m.random()
+	for s, sts := range (*m.Docs)[d].Sentenses {
+		for w := range sts.Words {
+			m.Vdsn[d][s][w] = r.Intn(m.window(d))
+			if r.Intn(2) == 0 {
+				m.Rdsn[d][s][w] = globalTopic
+				m.Zdsn[d][s][w] = r.Intn(m.GlobalK)
+			} else {
+				m.Rdsn[d][s][w] = localTopic
+				m.Zdsn[d][s][w] = r.Intn(m.LocalK)
 			}
-			ndsvd = append(ndsvd, ndsvs)
-			ndsd = append(ndsd, inflation)
 		}
-		m.Vdsn = append(m.Vdsn, vd)
-		m.Rdsn = append(m.Rdsn, rd)
-		m.Zdsn = append(m.Zdsn, zd)
-		m.Ndsv = append(m.Ndsv, ndsvd)
-		m.Nds = append(m.Nds, ndsd)
 	}
+}
 
-	glog.Info("initializing")
-	for d, doc := range *docs {
-		if doc.State == Holdout {
-			continue
+// allocDocument allocates the state of document d, which must be the next
+// document without state, with every word in the first window and global
+// topic. The window counts are sized for T, the largest window size.
+func (m *MGLDA) allocDocument(d int) {
+	doc := (*m.Docs)[d]
+	m.growDocuments(d + 1)
+
+	vd := [][]int{}
+	rd := [][]string{}
+	zd := [][]int{}
+	ndsvd := [][]float64{}
+	ndsd := []float64{}
+	windows := len(doc.Sentenses) + m.T
+	m.Ndvloc = append(m.Ndvloc, matrix.Numbers(windows, 1, m.Inflation).Array())
+	m.Ndvlocz = append(m.Ndvlocz, matrix.Numbers(windows, m.LocalK, m.Inflation).Arrays())
+	m.Ndv = append(m.Ndv, matrix.Numbers(windows, 1, m.Inflation).Array())
+	m.Ndvgl = append(m.Ndvgl, matrix.Numbers(windows, 1, m.Inflation).Array())
+
+	for _, sts := range doc.Sentenses {
+		vs := make([]int, len(sts.Words))
+		rs := make([]string, len(sts.Words))
+		zs := make([]int, len(sts.Words))
+		for w := range rs {
+			rs[w] = globalTopic
 		}
-		for s, sts := range doc.Sentenses {
-			for w, wd := range sts.Words {
-				v := m.Vdsn[d][s][w]
-				r := m.Rdsn[d][s][w]
-				z := m.Zdsn[d][s][w]
-				if r == globalTopic {
-					m.Nglzw.Set(z, wd, m.Nglzw.Get(z, wd)+1)
-					m.Nglz.Set(z, 0, m.Nglz.Get(z, 0)+1)
-					m.Ndvgl[d][s+v] += 1
-					m.Ndglz.Set(d, z, m.Ndglz.Get(d, z)+1)
-					m.Ndgl.Set(d, 0, m.Ndgl.Get(d, 0)+1)
-				} else {
-					m.Nloczw.Set(z, wd, m.Nloczw.Get(z, wd)+1)
-					m.Nlocz.Set(z, 0, m.Nlocz.Get(z, 0)+1)
-					m.Ndvloc[d][s+v] += 1
-					m.Ndvlocz[d][s+v][z] += 1
-				}
-				m.Ndsv[d][s][v] += 1
-				m.Nds[d][s] += 1
-				m.Ndv[d][s+v] += 1
-			}
+		vd = append(vd, vs)
+		rd = append(rd, rs)
+		zd = append(zd, zs)
+
+		ndsvs := []float64{}
+		for i := 0; i < m.T; i++ {
+			ndsvs = append(ndsvs, m.Inflation)
 		}
+		ndsvd = append(ndsvd, ndsvs)
+		ndsd = append(ndsd, m.Inflation)
 	}
-
-	return &m
+	m.Vdsn = append(m.Vdsn, vd)
+	m.Rdsn = append(m.Rdsn, rd)
+	m.Zdsn = append(m.Zdsn, zd)
+	m.Ndsv = append(m.Ndsv, ndsvd)
+	m.Nds = append(m.Nds, ndsd)
 }
 
 func (m *MGLDA) loadDocument(d int) {
 	for s, sts := range (*m.Docs)[d].Sentenses {
 		for w, wd := range sts.Words {
-			v := m.Vdsn[d][s][w]
-			r := m.Rdsn[d][s][w]
-			z := m.Zdsn[d][s][w]
-			if r == globalTopic {
-				m.Nglzw.Set(z, wd, m.Nglzw.Get(z, wd)+1)
-				m.Nglz.Set(z, 0, m.Nglz.Get(z, 0)+1)
-				m.Ndvgl[d][s+v] += 1
-				m.Ndglz.Set(d, z, m.Ndglz.Get(d, z)+1)
-				m.Ndgl.Set(d, 0, m.Ndgl.Get(d, 0)+1)
-			} else {
-				m.Nloczw.Set(z, wd, m.Nloczw.Get(z, wd)+1)
-				m.Nlocz.Set(z, 0, m.Nlocz.Get(z, 0)+1)
-				m.Ndvloc[d][s+v] += 1
-				m.Ndvlocz[d][s+v][z] += 1
-			}
-			m.Ndsv[d][s][v] += 1
-			m.Nds[d][s] += 1
-			m.Ndv[d][s+v] += 1
+			m.count(d, s, wd, m.Vdsn[d][s][w], m.Rdsn[d][s][w], m.Zdsn[d][s][w], 1)
 		}
 	}
 }
@@ -361,106 +599,58 @@ func (m *MGLDA) loadDocument(d int) {
 func (m *MGLDA) unloadDocument(d int) {
 	for s, sts := range (*m.Docs)[d].Sentenses {
 		for w, wd := range sts.Words {
-			v := m.Vdsn[d][s][w]
-			r := m.Rdsn[d][s][w]
-			z := m.Zdsn[d][s][w]
-			if r == globalTopic {
-				m.Nglzw.Set(z, wd, m.Nglzw.Get(z, wd)-1)
-				m.Nglz.Set(z, 0, m.Nglz.Get(z, 0)-1)
-				m.Ndvgl[d][s+v] -= 1
-				m.Ndglz.Set(d, z, m.Ndglz.Get(d, z)-1)
-				m.Ndgl.Set(d, 0, m.Ndgl.Get(d, 0)-1)
-			} else {
-				m.Nloczw.Set(z, wd, m.Nloczw.Get(z, wd)-1)
-				m.Nlocz.Set(z, 0, m.Nlocz.Get(z, 0)-1)
-				m.Ndvloc[d][s+v] -= 1
-				m.Ndvlocz[d][s+v][z] -= 1
-			}
-			m.Ndsv[d][s][v] -= 1
-			m.Nds[d][s] -= 1
-			m.Ndv[d][s+v] -= 1
+			m.count(d, s, wd, m.Vdsn[d][s][w], m.Rdsn[d][s][w], m.Zdsn[d][s][w], -1)
 		}
 	}
 }
 
 func GetWordTopicDist(m *MGLDA, vocabulary []string, wt *bufio.Writer) {
-	zGlCount := make([]int, m.GlobalK)
-	zLocCount := make([]int, m.LocalK)
-	wordGlCount := []map[int]int{}
-	wordLocCount := []map[int]int{}
-	for i := 0; i < m.GlobalK; i++ {
-		wordGlCount = append(wordGlCount, map[int]int{})
-	}
-	for i := 0; i < m.LocalK; i++ {
-		wordLocCount = append(wordLocCount, map[int]int{})
-	}
+	WriteTopics(m, vocabulary, DefaultTopWordsOptions(), wt)
+}
 
+// WriteTopics writes the top words of every global and local topic as
+// "word: prob (count)" lines under a header per topic.
+func WriteTopics(m *MGLDA, vocabulary []string, opt TopWordsOptions, wt *bufio.Writer) {
 	glog.Info("Get words distribution")
-	for d, doc := range *m.Docs {
-		for s, sent := range doc.Sentenses {
-			for w, wd := range sent.Words {
-				r := m.Rdsn[d][s][w]
-				z := m.Zdsn[d][s][w]
-				if r == globalTopic {
-					zGlCount[z] += 1
-					wordGlCount[z][wd] += 1
-				} else {
-					zLocCount[z] += 1
-					wordLocCount[z][wd] += 1
-				}
-			}
+	for _, kind := range []TopicKind{Global, Local} {
+		nz := m.Nglz
+		if kind == Local {
+			nz = m.Nlocz
 		}
-	}
-	glog.Info("Done dist")
-	phiGl, phiLoc := m.WordDist()
-	for i := 0; i < m.GlobalK; i++ {
-		header := fmt.Sprintf("-- global topic: %d (%d words)\n", i, zGlCount[i])
-		wt.WriteString(header)
-		glog.Info(header)
-		rows := phiGl.RowCopy(i)
-		idx := []int{}
-		for j := 0; j < len(rows); j++ {
-			idx = append(idx, j)
-		}
-		floats.Argsort(rows, idx)
-		for j := len(idx) - 1; j > len(idx)-topicLimit; j-- {
-			w := idx[j]
-			tp := fmt.Sprintf("%s: %f (%d)\n",
-				vocabulary[w], phiGl.Get(i, w),
-				wordGlCount[i][w])
-			wt.WriteString(tp)
-			glog.Info(tp)
-		}
-	}
-	for i := 0; i < m.LocalK; i++ {
-		header := fmt.Sprintf("-- local topic: %d (%d words)\n", i, zLocCount[i])
-		wt.WriteString(header)
-		glog.Info(header)
-		rows := phiLoc.RowCopy(i)
-		idx := []int{}
-		for j := 0; j < len(rows); j++ {
-			idx = append(idx, j)
-		}
-		floats.Argsort(rows, idx)
-		for j := len(idx) - 1; j > len(idx)-topicLimit; j-- {
-			w := idx[j]
-			tp := fmt.Sprintf("%s: %f (%d)\n",
-				vocabulary[w], phiLoc.Get(i, w),
-				wordLocCount[i][w])
-			wt.WriteString(tp)
-			glog.Info(tp)
+		for i, words := range m.TopWords(kind, vocabulary, opt) {
+			header := fmt.Sprintf("-- %s topic: %d%s (%d words)\n", kind, i,
+				nameSuffix(m.Labels.Name(kind, i)), int(nz.Get(i, 0)))
+			wt.WriteString(header)
+			glog.Info(header)
+			for _, word := range words {
+				tp := fmt.Sprintf("%s: %f (%d)\n", word.Label, word.Prob, word.Count)
+				wt.WriteString(tp)
+				glog.Info(tp)
+			}
 		}
 	}
-
 }
 
 func Learning(m *MGLDA, iteration int, vocabulary []string, wt *bufio.Writer) {
+	LearningWithOptions(m, iteration, vocabulary, DefaultTopWordsOptions(), wt)
+}
+
+// LearningWithOptions is Learning with the topic listing configured by opt.
+func LearningWithOptions(m *MGLDA, iteration int, vocabulary []string,
+	opt TopWordsOptions, wt *bufio.Writer) {
 	for i := 0; i < iteration; i++ {
 		wt.WriteString(fmt.Sprintf("==== %d-th inference ====\n", i))
 		glog.Info(fmt.Sprintf("==== %d-th inference ====\n", i))
-		m.Inference()
+		m.Train(1)
 		glog.Info("inference completed")
-		GetWordTopicDist(m, vocabulary, wt)
+		if m.HDP != nil {
+			wt.WriteString(fmt.Sprintf("global topics: %d\n", m.GlobalK))
+		}
+		if m.WindowGamma != nil {
+			wt.WriteString(fmt.Sprintf("window gamma: %v, window loglikelihood: %f\n",
+				m.WindowGamma, m.WindowLogLikelihood()))
+		}
+		WriteTopics(m, vocabulary, opt, wt)
 	}
 }
 
@@ -512,6 +702,7 @@ func EvaluateHoldout(m *MGLDA, trainBurnin int, testBurnin int, sampleSpace int,
 			ptr.State = Active
 			hmloglik := -100.0
 			m.loadDocument(dno)
+			m.fixed = true
 			for i := 0; i < testBurnin+sampleSpace; i++ {
 				m.Inference()
 				if i >= testBurnin {
@@ -519,6 +710,7 @@ func EvaluateHoldout(m *MGLDA, trainBurnin int, testBurnin int, sampleSpace int,
 					hmloglik = logAddition(hmloglik, beforeLoglik-afterLoglik)
 				}
 			}
+			m.fixed = false
 			hmloglik = math.Log(float64(sampleSpace)) - hmloglik
 			m.unloadDocument(dno)
 			ptr.State = Holdout
@@ -0,0 +1,172 @@
+package mglda
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// savedModel is the on-disk form of a trained MGLDA. The count matrices are
+// not stored; LoadModel rebuilds them from the assignments.
+type savedModel struct {
+	GlobalK        int          `json:"global_k"`
+	LocalK         int          `json:"local_k"`
+	Gamma          float64      `json:"gamma"`
+	GlobalAlpha    float64      `json:"global_alpha"`
+	LocalAlpha     float64      `json:"local_alpha"`
+	GlobalAlphaMix float64      `json:"global_alpha_mix"`
+	LocalAlphaMix  float64      `json:"local_alpha_mix"`
+	GlobalBeta     float64      `json:"global_beta"`
+	LocalBeta      float64      `json:"local_beta"`
+	T              int          `json:"t"`
+	W              int          `json:"w"`
+	Vocabulary     []string     `json:"vocabulary,omitempty"`
+	Docs           []Document   `json:"docs"`
+	Vdsn           [][][]int    `json:"v"`
+	Rdsn           [][][]string `json:"r"`
+	Zdsn           [][][]int    `json:"z"`
+	Trace          []float64    `json:"trace,omitempty"`
+	Ratings        *RatingModel `json:"ratings,omitempty"`
+	Seeds          []SeedWord   `json:"seeds,omitempty"`
+	Labels         TopicLabels  `json:"labels,omitempty"`
+	HDP            *HDP         `json:"hdp,omitempty"`
+	Windows        *WindowPrior `json:"windows,omitempty"`
+	WindowGamma    []float64    `json:"window_gamma,omitempty"`
+	LearnGamma     bool         `json:"learn_gamma,omitempty"`
+	Covariates     *Covariates  `json:"covariates,omitempty"`
+}
+
+// SaveModel writes the hyperparameters, documents and assignments of m,
+// together with its vocabulary, as JSON.
+func SaveModel(w io.Writer, m *MGLDA, vocabulary []string) error {
+	sm := savedModel{
+		GlobalK:        m.GlobalK,
+		LocalK:         m.LocalK,
+		Gamma:          m.Gamma,
+		GlobalAlpha:    m.GlobalAlpha,
+		LocalAlpha:     m.LocalAlpha,
+		GlobalAlphaMix: m.GlobalAlphaMix,
+		LocalAlphaMix:  m.LocalAlphaMix,
+		GlobalBeta:     m.GlobalBeta,
+		LocalBeta:      m.LocalBeta,
+		T:              m.T,
+		W:              m.W,
+		Vocabulary:     vocabulary,
+		Docs:           *m.Docs,
+		Vdsn:           m.Vdsn,
+		Rdsn:           m.Rdsn,
+		Zdsn:           m.Zdsn,
+		Trace:          m.Trace,
+		Ratings:        m.Ratings,
+		Seeds:          m.Seeds,
+		Labels:         m.Labels,
+		HDP:            m.HDP,
+		Windows:        m.Windows,
+		WindowGamma:    m.WindowGamma,
+		LearnGamma:     m.LearnGamma,
+		Covariates:     m.Covariates,
+	}
+	return json.NewEncoder(w).Encode(&sm)
+}
+
+// LoadModel reads a model written by SaveModel and rebuilds its counts. It
+// returns the model and its vocabulary.
+func LoadModel(r io.Reader) (*MGLDA, []string, error) {
+	sm := savedModel{}
+	if err := json.NewDecoder(r).Decode(&sm); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateDocuments(sm.Docs, sm.W); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateWindows(sm.Docs, sm.T); err != nil {
+		return nil, nil, err
+	}
+
+	docs := sm.Docs
+	m := newMGLDA(sm.GlobalK, sm.LocalK, sm.Gamma, sm.GlobalAlpha, sm.LocalAlpha,
+		sm.GlobalAlphaMix, sm.LocalAlphaMix, sm.GlobalBeta, sm.LocalBeta,
+		sm.T, sm.W, &docs)
+	for d := range docs {
+		m.initDocument(d)
+	}
+	if err := checkAssignments(m, &sm); err != nil {
+		return nil, nil, err
+	}
+	if sm.HDP != nil {
+		if err := checkHDP(m, sm.HDP); err != nil {
+			return nil, nil, err
+		}
+		m.HDP = sm.HDP
+	}
+	if sm.Windows != nil {
+		if err := checkWindowPrior(m, sm.Windows); err != nil {
+			return nil, nil, err
+		}
+		m.Windows = sm.Windows
+	}
+	if err := m.SetWindowGamma(sm.WindowGamma, sm.LearnGamma); err != nil {
+		return nil, nil, err
+	}
+	if sm.Covariates != nil {
+		if err := checkCovariates(m, sm.Covariates); err != nil {
+			return nil, nil, err
+		}
+		m.Covariates = sm.Covariates
+	}
+	if err := m.SetLabels(sm.Labels); err != nil {
+		return nil, nil, err
+	}
+	if err := m.setSeedWords(sm.Seeds); err != nil {
+		return nil, nil, err
+	}
+	m.Vdsn, m.Rdsn, m.Zdsn = sm.Vdsn, sm.Rdsn, sm.Zdsn
+	m.Trace = sm.Trace
+	for d, doc := range docs {
+		if doc.State == Holdout {
+			continue
+		}
+		m.loadDocument(d)
+	}
+	if sm.Ratings != nil {
+		if err := checkRatingModel(m, sm.Ratings); err != nil {
+			return nil, nil, err
+		}
+		m.Ratings = sm.Ratings
+		m.Ratings.refresh(m)
+	}
+	return m, sm.Vocabulary, nil
+}
+
+// checkAssignments verifies that the saved assignments match the shape of
+// the documents and the ranges of the model.
+func checkAssignments(m *MGLDA, sm *savedModel) error {
+	if len(sm.Vdsn) != len(sm.Docs) || len(sm.Rdsn) != len(sm.Docs) || len(sm.Zdsn) != len(sm.Docs) {
+		return fmt.Errorf("model: assignments for %d documents, want %d", len(sm.Zdsn), len(sm.Docs))
+	}
+	for d, doc := range sm.Docs {
+		if len(sm.Vdsn[d]) != len(doc.Sentenses) || len(sm.Rdsn[d]) != len(doc.Sentenses) ||
+			len(sm.Zdsn[d]) != len(doc.Sentenses) {
+			return fmt.Errorf("model: document %d: assignments do not match its sentences", d)
+		}
+		for s, sent := range doc.Sentenses {
+			if len(sm.Vdsn[d][s]) != len(sent.Words) || len(sm.Rdsn[d][s]) != len(sent.Words) ||
+				len(sm.Zdsn[d][s]) != len(sent.Words) {
+				return fmt.Errorf("model: document %d, sentence %d: assignments do not match its words", d, s)
+			}
+			for w := range sent.Words {
+				v, r, z := sm.Vdsn[d][s][w], sm.Rdsn[d][s][w], sm.Zdsn[d][s][w]
+				if v < 0 || v >= windowSize(doc, m.T) {
+					return fmt.Errorf("model: document %d, sentence %d: window %d out of range", d, s, v)
+				}
+				switch {
+				case r == globalTopic && z >= 0 && z < m.GlobalK:
+				case r == localTopic && z >= 0 && z < m.LocalK:
+				default:
+					return fmt.Errorf("model: document %d, sentence %d: invalid topic %s:%d", d, s, r, z)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package mglda
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveLoadModel(t *testing.T) {
+	d := append([]Document{}, docs...)
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &d)
+	m.Inference()
+
+	var buf bytes.Buffer
+	assert.Nil(t, SaveModel(&buf, m, vocabulary))
+	loaded, v, err := LoadModel(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, vocabulary, v)
+	assert.Equal(t, m.Zdsn, loaded.Zdsn)
+	assert.Equal(t, m.Nglzw.Array(), loaded.Nglzw.Array())
+	assert.Equal(t, m.Nloczw.Array(), loaded.Nloczw.Array())
+	assert.Equal(t, m.Ndvlocz, loaded.Ndvlocz)
+	assert.Equal(t, m.LogLikelihood(), loaded.LogLikelihood())
+}
+
+func TestInfer(t *testing.T) {
+	d := append([]Document{}, docs...)
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &d)
+	first := m.Infer([]Document{docs[0]}, 2)
+	assert.Equal(t, 1, first)
+	assert.Equal(t, 2, len(*m.Docs))
+	assert.Equal(t, Active, (*m.Docs)[0].State)
+	assert.Equal(t, Frozen, (*m.Docs)[1].State)
+
+	gl, loc := m.DocTopicDist(first)
+	var sum float64
+	for _, p := range gl {
+		sum += p
+	}
+	assert.InDelta(t, 1, sum, 1e-9)
+	sum = 0
+	for _, p := range loc {
+		sum += p
+	}
+	assert.InDelta(t, 1, sum, 1e-9)
+}
+
+func TestInferFixesPriors(t *testing.T) {
+	d := reviews(20)
+	m := NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3, 6, &d)
+	assert.Nil(t, m.EnableHDP(1, 1))
+	assert.Nil(t, m.LearnWindows(1))
+	assert.Nil(t, m.SetWindowGamma(nil, true))
+	m.Train(3)
+	globalK := m.GlobalK
+	beta := append([]float64{}, m.HDP.Beta...)
+	weights := append([]float64{}, m.Windows.Weights...)
+	gamma := append([]float64{}, m.WindowGamma...)
+
+	first := m.Infer(reviews(10), 5)
+	assert.Equal(t, globalK, m.GlobalK)
+	assert.Equal(t, beta, m.HDP.Beta)
+	assert.Equal(t, weights, m.Windows.Weights)
+	assert.Equal(t, gamma, m.WindowGamma)
+	for dd := first; dd < len(*m.Docs); dd++ {
+		for s := range m.Zdsn[dd] {
+			for w, z := range m.Zdsn[dd][s] {
+				if m.Rdsn[dd][s][w] == globalTopic {
+					assert.True(t, z < globalK)
+				}
+			}
+		}
+	}
+
+	// training afterwards updates the priors again
+	m.Train(1)
+	assert.NotEqual(t, beta, m.HDP.Beta)
+	assert.NotEqual(t, weights, m.Windows.Weights)
+	assert.NotEqual(t, gamma, m.WindowGamma)
+}
@@ -0,0 +1,123 @@
+package mglda
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TopicSummary describes one topic for the structured outputs.
+type TopicSummary struct {
+	Kind        TopicKind `json:"kind"`
+	ID          int       `json:"id"`
+	Name        string    `json:"name,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tokens      int       `json:"tokens"`
+	Words       []TopWord `json:"words"`
+}
+
+// Topics summarizes every global and then every local topic with its top
+// words.
+func (m *MGLDA) Topics(vocabulary []string, opt TopWordsOptions) []TopicSummary {
+	topics := []TopicSummary{}
+	for _, kind := range []TopicKind{Global, Local} {
+		nz := m.Nglz
+		if kind == Local {
+			nz = m.Nlocz
+		}
+		for i, words := range m.TopWords(kind, vocabulary, opt) {
+			topics = append(topics, TopicSummary{
+				Kind:        kind,
+				ID:          i,
+				Name:        m.Labels.Name(kind, i),
+				Description: m.Labels.Description(kind, i),
+				Tokens:      int(nz.Get(i, 0)),
+				Words:       words,
+			})
+		}
+	}
+	return topics
+}
+
+// WriteTopicsJSON writes topics as a JSON array.
+func WriteTopicsJSON(w io.Writer, topics []TopicSummary) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(topics)
+}
+
+// WriteTopicsCSV writes topics with one row per top word, separated by sep
+// (',' for CSV, '\t' for TSV).
+func WriteTopicsCSV(w io.Writer, topics []TopicSummary, sep rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = sep
+	header := []string{"kind", "topic", "name", "tokens", "rank", "word", "id",
+		"prob", "count", "relevance", "lift", "frex"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	for _, t := range topics {
+		for rank, word := range t.Words {
+			row := []string{string(t.Kind), strconv.Itoa(t.ID), t.Name, strconv.Itoa(t.Tokens),
+				strconv.Itoa(rank), word.Label, strconv.Itoa(word.Word),
+				f(word.Prob), strconv.Itoa(word.Count), f(word.Relevance), f(word.Lift), f(word.FREX)}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteNpy writes a row-major matrix of float64 in NumPy's .npy format
+// (version 1.0).
+func WriteNpy(w io.Writer, rows [][]float64) error {
+	cols := 0
+	if len(rows) > 0 {
+		cols = len(rows[0])
+	}
+	header := fmt.Sprintf("{'descr': '<f8', 'fortran_order': False, 'shape': (%d, %d), }", len(rows), cols)
+	// magic (6) + version (2) + header length (2) + header, padded with
+	// spaces and terminated by a newline to a multiple of 64 bytes
+	pad := 64 - (10+len(header)+1)%64
+	if pad == 64 {
+		pad = 0
+	}
+	header += strings.Repeat(" ", pad) + "\n"
+
+	if _, err := w.Write([]byte("\x93NUMPY\x01\x00")); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint16(len(header))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if len(row) != cols {
+			return fmt.Errorf("npy: ragged matrix")
+		}
+		if err := binary.Write(w, binary.LittleEndian, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Theta returns the global and local topic distributions of every document,
+// one row per document as in DocTopicDist.
+func (m *MGLDA) Theta() ([][]float64, [][]float64) {
+	thetaGl := make([][]float64, len(*m.Docs))
+	thetaLoc := make([][]float64, len(*m.Docs))
+	for d := range *m.Docs {
+		thetaGl[d], thetaLoc[d] = m.DocTopicDist(d)
+	}
+	return thetaGl, thetaLoc
+}
@@ -0,0 +1,50 @@
+package mglda
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteNpy(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteNpy(&buf, [][]float64{{1, 2, 3}, {4, 5, 6}}))
+	b := buf.Bytes()
+	assert.Equal(t, "\x93NUMPY\x01\x00", string(b[:8]))
+	headerLen := int(binary.LittleEndian.Uint16(b[8:10]))
+	assert.Equal(t, 0, (10+headerLen)%64)
+	assert.Contains(t, string(b[10:10+headerLen]), "'shape': (2, 3)")
+	assert.Equal(t, 10+headerLen+6*8, len(b))
+
+	assert.NotNil(t, WriteNpy(&buf, [][]float64{{1, 2}, {3}}))
+}
+
+func TestWriteTopics(t *testing.T) {
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &docs)
+	opt := DefaultTopWordsOptions()
+	opt.N = 3
+	topics := m.Topics(vocabulary, opt)
+	assert.Equal(t, 6, len(topics))
+	assert.Equal(t, Local, topics[4].Kind)
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteTopicsJSON(&buf, topics))
+	decoded := []TopicSummary{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, topics, decoded)
+
+	buf.Reset()
+	assert.Nil(t, WriteTopicsCSV(&buf, topics, '\t'))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 1+6*3, len(lines))
+	assert.True(t, strings.HasPrefix(lines[1], "global\t0\t"))
+
+	thetaGl, thetaLoc := m.Theta()
+	assert.Equal(t, len(docs), len(thetaGl))
+	assert.Equal(t, 2, len(thetaLoc[0]))
+}
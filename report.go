@@ -0,0 +1,271 @@
+package mglda
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+)
+
+// ReportOptions configures WriteReport.
+type ReportOptions struct {
+	Title string
+	// TopWords configures the word lists of the topics.
+	TopWords TopWordsOptions
+	// Examples is the number of documents shown with coloured sentences.
+	Examples int
+}
+
+// DefaultReportOptions returns the options used by the mglda command.
+func DefaultReportOptions() ReportOptions {
+	opt := DefaultTopWordsOptions()
+	opt.N = 15
+	return ReportOptions{Title: "MG-LDA model report", TopWords: opt, Examples: 5}
+}
+
+type reportBar struct {
+	Label string
+	Value float64
+	Width float64
+	Color template.CSS
+}
+
+type reportTopic struct {
+	Kind        TopicKind
+	ID          int
+	Name        string
+	Description string
+	Tokens      int
+	Prevalence  float64
+	Color       template.CSS
+	Words       []reportBar
+}
+
+type reportSentence struct {
+	Text   string
+	Topic  int
+	Window int
+	Color  template.CSS
+}
+
+type reportDocument struct {
+	ID        int
+	Sentences []reportSentence
+}
+
+type reportData struct {
+	Title       string
+	Model       *MGLDA
+	Documents   int
+	Tokens      int
+	Share       []reportBar
+	Prevalence  []reportBar
+	Topics      []reportTopic
+	Examples    []reportDocument
+	Curve       string
+	CurveMin    float64
+	CurveMax    float64
+	CurveLength int
+}
+
+// localColor returns a colour per local topic spread around the hue circle.
+func localColor(z, k int) template.CSS {
+	return template.CSS(fmt.Sprintf("hsl(%d, 70%%, 80%%)", z*360/k))
+}
+
+// argmax returns the index of the largest value, the first on ties.
+func argmax(values []float64) int {
+	best := 0
+	for i, v := range values {
+		if v > values[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// curvePoints returns the SVG polyline points of trace scaled to width x
+// height.
+func curvePoints(trace []float64, width, height float64) (string, float64, float64) {
+	if len(trace) == 0 {
+		return "", 0, 0
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range trace {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	points := []string{}
+	for i, v := range trace {
+		x := 0.0
+		if len(trace) > 1 {
+			x = float64(i) / float64(len(trace)-1) * width
+		}
+		y := height / 2
+		if hi > lo {
+			y = height - (v-lo)/(hi-lo)*height
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	return strings.Join(points, " "), lo, hi
+}
+
+// WriteReport writes a self-contained HTML report of m: the global and local
+// token share, topic prevalence, the top words of every topic, example
+// documents with every sentence coloured by its dominant local topic and
+// window, and the log-likelihood trace of training.
+func WriteReport(w io.Writer, m *MGLDA, vocabulary []string, opt ReportOptions) error {
+	data := reportData{Title: opt.Title, Model: m}
+
+	thetaGl := make([]float64, m.GlobalK)
+	thetaLoc := make([]float64, m.LocalK)
+	for d, doc := range *m.Docs {
+		if doc.State == Holdout {
+			continue
+		}
+		data.Documents++
+		data.Tokens += doc.NumberOfWords()
+		gl, loc := m.DocTopicDist(d)
+		for z := range gl {
+			thetaGl[z] += gl[z]
+		}
+		for z := range loc {
+			thetaLoc[z] += loc[z]
+		}
+	}
+
+	var nGl, nLoc float64
+	for z := 0; z < m.GlobalK; z++ {
+		nGl += m.Nglz.Get(z, 0)
+	}
+	for z := 0; z < m.LocalK; z++ {
+		nLoc += m.Nlocz.Get(z, 0)
+	}
+	if nGl+nLoc > 0 {
+		data.Share = []reportBar{
+			{Label: "global", Value: nGl / (nGl + nLoc), Width: 100 * nGl / (nGl + nLoc), Color: "#9bb"},
+			{Label: "local", Value: nLoc / (nGl + nLoc), Width: 100 * nLoc / (nGl + nLoc), Color: "#db9"},
+		}
+	}
+
+	for _, kind := range []TopicKind{Global, Local} {
+		theta, nz := thetaGl, m.Nglz
+		if kind == Local {
+			theta, nz = thetaLoc, m.Nlocz
+		}
+		for z, words := range m.TopWords(kind, vocabulary, opt.TopWords) {
+			t := reportTopic{Kind: kind, ID: z, Tokens: int(nz.Get(z, 0)), Color: "#9bb",
+				Name: m.Labels.Name(kind, z), Description: m.Labels.Description(kind, z)}
+			if data.Documents > 0 {
+				t.Prevalence = theta[z] / float64(data.Documents)
+			}
+			if kind == Local {
+				t.Color = localColor(z, m.LocalK)
+			}
+			top := 0.0
+			if len(words) > 0 {
+				top = words[0].Prob
+			}
+			for _, word := range words {
+				bar := reportBar{Label: word.Label, Value: word.Prob, Color: t.Color}
+				if top > 0 {
+					bar.Width = 100 * word.Prob / top
+				}
+				t.Words = append(t.Words, bar)
+			}
+			data.Topics = append(data.Topics, t)
+			data.Prevalence = append(data.Prevalence, reportBar{
+				Label: fmt.Sprintf("%s %d%s", kind, z, nameSuffix(t.Name)),
+				Value: t.Prevalence,
+				Width: 100 * t.Prevalence,
+				Color: t.Color,
+			})
+		}
+	}
+
+	for d, doc := range *m.Docs {
+		if len(data.Examples) >= opt.Examples {
+			break
+		}
+		if doc.State == Holdout {
+			continue
+		}
+		example := reportDocument{ID: d}
+		for s, sent := range doc.Sentenses {
+			words := []string{}
+			for _, wd := range sent.Words {
+				words = append(words, wordLabel(vocabulary, wd))
+			}
+			z := argmax(m.SentenceLocalDist(d, s))
+			example.Sentences = append(example.Sentences, reportSentence{
+				Text:   strings.Join(words, " "),
+				Topic:  z,
+				Window: s + argmax(m.Ndsv[d][s]),
+				Color:  localColor(z, m.LocalK),
+			})
+		}
+		data.Examples = append(data.Examples, example)
+	}
+
+	data.Curve, data.CurveMin, data.CurveMax = curvePoints(m.Trace, 600, 200)
+	data.CurveLength = len(m.Trace)
+	return reportTemplate.Execute(w, &data)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(v float64) string { return fmt.Sprintf("%.1f%%", 100*v) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h2 { border-bottom: 1px solid #ccc; }
+.bar { display: flex; align-items: center; margin: 1px 0; }
+.bar .label { width: 12em; overflow: hidden; white-space: nowrap; }
+.bar .fill { height: 1em; }
+.bar .value { margin-left: 0.5em; font-size: small; color: #666; }
+.topics { display: flex; flex-wrap: wrap; }
+.topic { width: 24em; margin: 0 1em 1em 0; }
+.sentence { padding: 0 2px; }
+.sentence sub { color: #666; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Documents}} documents, {{.Tokens}} words, {{.Model.W}} distinct words.
+{{.Model.GlobalK}} global and {{.Model.LocalK}} local topics, windows of {{.Model.T}} sentences.</p>
+
+<h2>Global and local tokens</h2>
+{{range .Share}}<div class="bar"><span class="label">{{.Label}}</span><span class="fill" style="width: {{.Width}}%; background: {{.Color}}"></span><span class="value">{{percent .Value}}</span></div>
+{{end}}
+
+<h2>Topic prevalence</h2>
+{{range .Prevalence}}<div class="bar"><span class="label">{{.Label}}</span><span class="fill" style="width: {{.Width}}%; background: {{.Color}}"></span><span class="value">{{percent .Value}}</span></div>
+{{end}}
+
+<h2>Topics</h2>
+<div class="topics">
+{{range .Topics}}<div class="topic">
+<h3>{{.Kind}} topic {{.ID}}{{with .Name}}: {{.}}{{end}} <small>({{.Tokens}} words, {{percent .Prevalence}})</small></h3>
+{{with .Description}}<p>{{.}}</p>
+{{end}}{{range .Words}}<div class="bar"><span class="label">{{.Label}}</span><span class="fill" style="width: {{.Width}}%; background: {{.Color}}"></span><span class="value">{{printf "%.4f" .Value}}</span></div>
+{{end}}</div>
+{{end}}</div>
+
+<h2>Example documents</h2>
+<p>Sentences are coloured by their dominant local topic; the subscript is the local topic and the dominant window.</p>
+{{range .Examples}}<h3>Document {{.ID}}</h3>
+<p>{{range .Sentences}}<span class="sentence" style="background: {{.Color}}">{{.Text}} <sub>{{.Topic}}/w{{.Window}}</sub></span> {{end}}</p>
+{{end}}
+
+<h2>Training</h2>
+{{if .Curve}}<p>Joint log-likelihood over {{.CurveLength}} sweeps, from {{printf "%.1f" .CurveMin}} to {{printf "%.1f" .CurveMax}}.</p>
+<svg width="620" height="220" viewBox="-10 -10 620 220"><polyline fill="none" stroke="#369" stroke-width="2" points="{{.Curve}}"/></svg>
+{{else}}<p>The model has no training trace.</p>
+{{end}}
+</body>
+</html>
+`))
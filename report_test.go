@@ -0,0 +1,24 @@
+package mglda
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteReport(t *testing.T) {
+	d := append([]Document{}, docs...)
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &d)
+	m.Train(3)
+	assert.Equal(t, 3, len(m.Trace))
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteReport(&buf, m, vocabulary, DefaultReportOptions()))
+	html := buf.String()
+	assert.Contains(t, html, "<polyline")
+	assert.Contains(t, html, "local topic 1")
+	assert.Contains(t, html, "company money email")
+	assert.NotContains(t, html, "ZgotmplZ")
+}
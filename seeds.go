@@ -0,0 +1,161 @@
+package mglda
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// SeedTopic anchors one topic to a named aspect by raising the topic word
+// prior of its seed words. Boost overrides the boost of the SeedConfig.
+type SeedTopic struct {
+	Kind  TopicKind `json:"kind"`
+	Topic int       `json:"topic"`
+	Name  string    `json:"name,omitempty"`
+	Words []string  `json:"words"`
+	Boost float64   `json:"boost,omitempty"`
+}
+
+// SeedConfig is the seed word file: the prior of a seed word in its topic
+// is the topic word prior (GlobalBeta or LocalBeta) times the boost.
+type SeedConfig struct {
+	Boost  float64     `json:"boost"`
+	Topics []SeedTopic `json:"topics"`
+}
+
+// SeedWord is the resolved prior of one seed word in one topic.
+type SeedWord struct {
+	Kind  TopicKind `json:"kind"`
+	Topic int       `json:"topic"`
+	Word  int       `json:"word"`
+	Beta  float64   `json:"beta"`
+}
+
+// seedPrior holds the asymmetric topic word priors of one kind of topic.
+// Words without a seed keep the symmetric prior.
+type seedPrior struct {
+	words []map[int]float64
+	sum   []float64
+}
+
+// ReadSeeds reads a seed word file in json.
+func ReadSeeds(r io.Reader) (*SeedConfig, error) {
+	seeds := SeedConfig{Boost: 1}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&seeds); err != nil {
+		return nil, fmt.Errorf("seeds: %v", err)
+	}
+	return &seeds, nil
+}
+
+// SetSeeds resolves the seed words of seeds against vocabulary and sets them
+// as the priors of m. Seed words missing from the vocabulary are skipped
+// with a warning, and named seed topics without a label are labelled. The
+// counts are not touched, so seeds are best set before training starts.
+func (m *MGLDA) SetSeeds(seeds *SeedConfig, vocabulary []string) error {
+	ids := map[string]int{}
+	for i, word := range vocabulary {
+		ids[word] = i
+	}
+	words := []SeedWord{}
+	for _, st := range seeds.Topics {
+		k := m.GlobalK
+		if st.Kind == Local {
+			k = m.LocalK
+		} else if st.Kind != Global {
+			return fmt.Errorf("seeds: invalid topic kind %q", st.Kind)
+		}
+		if st.Topic < 0 || st.Topic >= k {
+			return fmt.Errorf("seeds: %s topic %d out of range", st.Kind, st.Topic)
+		}
+		boost := st.Boost
+		if boost == 0 {
+			boost = seeds.Boost
+		}
+		if boost <= 0 {
+			return fmt.Errorf("seeds: %s topic %d: boost must be positive", st.Kind, st.Topic)
+		}
+		beta := m.GlobalBeta
+		if st.Kind == Local {
+			beta = m.LocalBeta
+		}
+		for _, word := range st.Words {
+			w, ok := ids[word]
+			if !ok {
+				glog.Warningf("seeds: %s topic %d (%s): %q is not in the vocabulary", st.Kind, st.Topic, st.Name, word)
+				continue
+			}
+			words = append(words, SeedWord{Kind: st.Kind, Topic: st.Topic, Word: w, Beta: beta * boost})
+		}
+		if st.Name != "" {
+			m.Labels = m.Labels.Merge(TopicLabels{{Kind: st.Kind, Topic: st.Topic, Name: st.Name,
+				Description: "seeded with " + strings.Join(st.Words, ", ")}})
+		}
+	}
+	return m.setSeedWords(words)
+}
+
+// setSeedWords sets the resolved seed words as the priors of m.
+func (m *MGLDA) setSeedWords(words []SeedWord) error {
+	newPrior := func(k int, beta float64) *seedPrior {
+		p := &seedPrior{words: make([]map[int]float64, k), sum: make([]float64, k)}
+		for z := range p.words {
+			p.words[z] = map[int]float64{}
+			p.sum[z] = float64(m.W) * beta
+		}
+		return p
+	}
+	global, local := newPrior(m.GlobalK, m.GlobalBeta), newPrior(m.LocalK, m.LocalBeta)
+	for _, sw := range words {
+		p, k, beta := global, m.GlobalK, m.GlobalBeta
+		if sw.Kind == Local {
+			p, k, beta = local, m.LocalK, m.LocalBeta
+		}
+		if sw.Topic < 0 || sw.Topic >= k || sw.Word < 0 || sw.Word >= m.W || sw.Beta <= 0 {
+			return fmt.Errorf("seeds: invalid seed word %d of %s topic %d", sw.Word, sw.Kind, sw.Topic)
+		}
+		old, ok := p.words[sw.Topic][sw.Word]
+		if !ok {
+			old = beta
+		}
+		p.words[sw.Topic][sw.Word] = sw.Beta
+		p.sum[sw.Topic] += sw.Beta - old
+	}
+	m.Seeds = words
+	m.globalSeeds, m.localSeeds = global, local
+	if len(words) == 0 {
+		m.globalSeeds, m.localSeeds = nil, nil
+	}
+	return nil
+}
+
+// beta returns the prior of word w in topic z of the given kind.
+func (m *MGLDA) beta(kind TopicKind, z, w int) float64 {
+	p, beta := m.globalSeeds, m.GlobalBeta
+	if kind == Local {
+		p, beta = m.localSeeds, m.LocalBeta
+	}
+	if p != nil {
+		if b, ok := p.words[z][w]; ok {
+			return b
+		}
+	}
+	return beta
+}
+
+// betaSum returns the sum of the priors of all words in topic z of the
+// given kind.
+func (m *MGLDA) betaSum(kind TopicKind, z int) float64 {
+	p, beta := m.globalSeeds, m.GlobalBeta
+	if kind == Local {
+		p, beta = m.localSeeds, m.LocalBeta
+	}
+	if p != nil {
+		return p.sum[z]
+	}
+	return float64(m.W) * beta
+}
@@ -0,0 +1,55 @@
+package mglda
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSeeds(t *testing.T) {
+	seeds, err := ReadSeeds(strings.NewReader(`{"boost": 50, "topics": [
+		{"kind": "local", "topic": 1, "name": "mail", "words": ["email", "send", "nosuchword"]}]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 50.0, seeds.Boost)
+	assert.Equal(t, "mail", seeds.Topics[0].Name)
+
+	_, err = ReadSeeds(strings.NewReader(`{"bost": 50}`))
+	assert.NotNil(t, err)
+}
+
+func TestSetSeeds(t *testing.T) {
+	d := append([]Document{}, docs...)
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &d)
+	assert.NotNil(t, m.SetSeeds(&SeedConfig{Boost: 10, Topics: []SeedTopic{{Kind: Local, Topic: 2}}}, vocabulary))
+	assert.NotNil(t, m.SetSeeds(&SeedConfig{Boost: 10, Topics: []SeedTopic{{Kind: "other"}}}, vocabulary))
+	assert.NotNil(t, m.SetSeeds(&SeedConfig{Topics: []SeedTopic{{Kind: Global, Boost: -1}}}, vocabulary))
+
+	seeds := &SeedConfig{Boost: 100, Topics: []SeedTopic{
+		{Kind: Local, Topic: 1, Words: []string{"email", "nosuchword"}},
+		{Kind: Global, Topic: 0, Words: []string{"money"}, Boost: 20},
+	}}
+	assert.Nil(t, m.SetSeeds(seeds, vocabulary))
+	assert.Equal(t, 2, len(m.Seeds))
+	email := m.Seeds[0].Word
+	assert.Equal(t, "email", vocabulary[email])
+	assert.InDelta(t, 10, m.beta(Local, 1, email), 1e-12)
+	assert.InDelta(t, 0.1, m.beta(Local, 0, email), 1e-12)
+	assert.InDelta(t, float64(m.W)*0.1+9.9, m.betaSum(Local, 1), 1e-9)
+	assert.InDelta(t, 2, m.beta(Global, 0, m.Seeds[1].Word), 1e-12)
+
+	m.Train(5)
+	_, phiLoc := m.WordDist()
+	assert.True(t, phiLoc.Get(1, email) > phiLoc.Get(0, email))
+	top := m.TopWords(Local, vocabulary, DefaultTopWordsOptions())
+	assert.Equal(t, "email", top[1][0].Label)
+
+	var buf bytes.Buffer
+	assert.Nil(t, SaveModel(&buf, m, vocabulary))
+	loaded, _, err := LoadModel(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, m.Seeds, loaded.Seeds)
+	assert.InDelta(t, m.LogLikelihood(), loaded.LogLikelihood(), 1e-9)
+}
@@ -0,0 +1,146 @@
+package mglda
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RepresentativeSentence is a sentence scored for one local topic.
+type RepresentativeSentence struct {
+	Doc      int     `json:"doc"`
+	Sentence int     `json:"sentence"`
+	Score    float64 `json:"score"`
+	Words    []int   `json:"words"`
+	Text     string  `json:"text"`
+}
+
+// sentenceHeap is a min-heap on score, so the weakest sentence is dropped
+// first when the heap grows beyond k.
+type sentenceHeap []RepresentativeSentence
+
+func (h sentenceHeap) Len() int { return len(h) }
+func (h sentenceHeap) Less(i, j int) bool {
+	if h[i].Score != h[j].Score {
+		return h[i].Score < h[j].Score
+	}
+	// later sentences lose ties
+	if h[i].Doc != h[j].Doc {
+		return h[i].Doc > h[j].Doc
+	}
+	return h[i].Sentence > h[j].Sentence
+}
+func (h sentenceHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *sentenceHeap) Push(x interface{}) { *h = append(*h, x.(RepresentativeSentence)) }
+func (h *sentenceHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func sentenceKey(words []int) string {
+	parts := make([]string, len(words))
+	for i, wd := range words {
+		parts[i] = fmt.Sprint(wd)
+	}
+	return strings.Join(parts, " ")
+}
+
+// SentenceScore scores sentence s of document d for local topic z: the
+// local topic posterior of the sentence (see SentenceLocalDist) times the
+// smoothed share of its words assigned to z.
+func (m *MGLDA) SentenceScore(d, s, z int) float64 {
+	return m.sentenceScores(d, s)[z]
+}
+
+func (m *MGLDA) sentenceScores(d, s int) []float64 {
+	scores := m.SentenceLocalDist(d, s)
+	counts := make([]float64, m.LocalK)
+	for w := range (*m.Docs)[d].Sentenses[s].Words {
+		if m.Rdsn[d][s][w] == localTopic {
+			counts[m.Zdsn[d][s][w]]++
+		}
+	}
+	n := float64(len((*m.Docs)[d].Sentenses[s].Words))
+	for z := range scores {
+		scores[z] *= (counts[z] + m.LocalAlpha) / (n + float64(m.LocalK)*m.LocalAlpha)
+	}
+	return scores
+}
+
+// RepresentativeSentences returns the k highest scoring sentences of every
+// local topic, ordered by decreasing score. Sentences with fewer than
+// minWords words are skipped, and of sentences with identical words only
+// the best scoring one is kept. Holdout documents are left out. vocabulary
+// may be nil.
+func (m *MGLDA) RepresentativeSentences(k, minWords int, vocabulary []string) [][]RepresentativeSentence {
+	heaps := make([]sentenceHeap, m.LocalK)
+	keys := make([]map[string]bool, m.LocalK)
+	for z := range keys {
+		keys[z] = map[string]bool{}
+	}
+
+	for d, doc := range *m.Docs {
+		if doc.State == Holdout {
+			continue
+		}
+		for s, sent := range doc.Sentenses {
+			if len(sent.Words) == 0 || len(sent.Words) < minWords {
+				continue
+			}
+			key := sentenceKey(sent.Words)
+			for z, score := range m.sentenceScores(d, s) {
+				rs := RepresentativeSentence{Doc: d, Sentence: s, Score: score, Words: sent.Words}
+				if keys[z][key] {
+					// keep the better scoring copy
+					for i := range heaps[z] {
+						if sentenceKey(heaps[z][i].Words) == key && score > heaps[z][i].Score {
+							heaps[z][i] = rs
+							heap.Fix(&heaps[z], i)
+						}
+					}
+					continue
+				}
+				if len(heaps[z]) == k && (k == 0 || score <= heaps[z][0].Score) {
+					continue
+				}
+				heap.Push(&heaps[z], rs)
+				keys[z][key] = true
+				if len(heaps[z]) > k {
+					dropped := heap.Pop(&heaps[z]).(RepresentativeSentence)
+					delete(keys[z], sentenceKey(dropped.Words))
+				}
+			}
+		}
+	}
+
+	result := make([][]RepresentativeSentence, m.LocalK)
+	for z := range heaps {
+		sentences := []RepresentativeSentence(heaps[z])
+		sort.Slice(sentences, func(i, j int) bool { return sentenceHeap(sentences).Less(j, i) })
+		for i := range sentences {
+			words := make([]string, len(sentences[i].Words))
+			for j, wd := range sentences[i].Words {
+				words[j] = wordLabel(vocabulary, wd)
+			}
+			sentences[i].Text = strings.Join(words, " ")
+		}
+		result[z] = sentences
+	}
+	return result
+}
+
+// WriteRepresentativeSentences writes the sentences of every local topic
+// as "score doc:sentence text" lines under a header per topic, named by
+// labels.
+func WriteRepresentativeSentences(wt *bufio.Writer, sentences [][]RepresentativeSentence, labels TopicLabels) {
+	for z, top := range sentences {
+		wt.WriteString(fmt.Sprintf("-- local topic: %d%s\n", z, nameSuffix(labels.Name(Local, z))))
+		for _, s := range top {
+			wt.WriteString(fmt.Sprintf("%f %d:%d %s\n", s.Score, s.Doc, s.Sentence, s.Text))
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package mglda
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepresentativeSentences(t *testing.T) {
+	d := append([]Document{}, docs...)
+	// a second copy of the first document must not repeat its sentences
+	d = append(d, docs[0])
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &d)
+	m.Train(2)
+
+	top := m.RepresentativeSentences(3, 2, vocabulary)
+	assert.Equal(t, 2, len(top))
+	for z, sentences := range top {
+		assert.Equal(t, 3, len(sentences))
+		seen := map[string]bool{}
+		for i, s := range sentences {
+			assert.True(t, len(s.Words) >= 2)
+			assert.False(t, seen[s.Text])
+			seen[s.Text] = true
+			assert.InDelta(t, m.SentenceScore(s.Doc, s.Sentence, z), s.Score, 1e-12)
+			if i > 0 {
+				assert.True(t, sentences[i-1].Score >= s.Score)
+			}
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package mglda
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/skelterjohn/go.matrix"
+)
+
+// SVIOptions configures the stochastic variational inference trainer.
+type SVIOptions struct {
+	// BatchSize is the number of documents per mini-batch.
+	BatchSize int
+	// Tau0 and Kappa set the learning rate (Tau0 + t)^-Kappa of update t;
+	// Kappa in (0.5, 1] guarantees convergence.
+	Tau0  float64
+	Kappa float64
+	// LocalIterations is the number of coordinate ascent rounds of the
+	// variational parameters of a document.
+	LocalIterations int
+}
+
+// DefaultSVIOptions returns the options used by the mglda command.
+func DefaultSVIOptions() SVIOptions {
+	return SVIOptions{BatchSize: 256, Tau0: 64, Kappa: 0.7, LocalIterations: 20}
+}
+
+// SVI trains MG-LDA by stochastic variational inference (Hoffman et al.,
+// 2013) under the fully factorized mean field family. The topic word
+// distributions have Dirichlet posteriors LambdaGl and LambdaLoc, updated
+// from mini-batches of documents by natural gradient steps; every word has
+// a responsibility over the window, global or local kind and topic it is
+// drawn from.
+type SVI struct {
+	GlobalK        int
+	LocalK         int
+	Gamma          float64
+	GlobalAlpha    float64
+	LocalAlpha     float64
+	GlobalAlphaMix float64
+	LocalAlphaMix  float64
+	GlobalBeta     float64
+	LocalBeta      float64
+	T              int
+	W              int
+	// D is the number of documents of the corpus, which scales the
+	// statistics of a mini-batch.
+	D         int
+	Options   SVIOptions
+	LambdaGl  [][]float64
+	LambdaLoc [][]float64
+	// Steps is the number of mini-batch updates so far.
+	Steps int
+
+	// rng draws the initial topics and the mini-batches.
+	rng *rand.Rand
+}
+
+// NewSVI returns a trainer for a corpus of d documents with topics and
+// mini-batches drawn from a source seeded by seed, which leaves the default
+// source untouched.
+func NewSVI(globalK, localK int, gamma, globalAlpha, localAlpha,
+	globalAlphaMix, localAlphaMix, globalBeta, localBeta float64,
+	t, w, d int, opt SVIOptions, seed int64) *SVI {
+	s := &SVI{
+		GlobalK:        globalK,
+		LocalK:         localK,
+		Gamma:          gamma,
+		GlobalAlpha:    globalAlpha,
+		LocalAlpha:     localAlpha,
+		GlobalAlphaMix: globalAlphaMix,
+		LocalAlphaMix:  localAlphaMix,
+		GlobalBeta:     globalBeta,
+		LocalBeta:      localBeta,
+		T:              t,
+		W:              w,
+		D:              d,
+		Options:        opt,
+		rng:            rand.New(rand.NewSource(seed)),
+	}
+	init := func(k int, beta float64) [][]float64 {
+		lambda := zeros2(k, w)
+		for z := range lambda {
+			for v := range lambda[z] {
+				lambda[z][v] = beta + 0.5 + s.rng.Float64()
+			}
+		}
+		return lambda
+	}
+	s.LambdaGl = init(globalK, globalBeta)
+	s.LambdaLoc = init(localK, localBeta)
+	return s
+}
+
+// digamma returns the logarithmic derivative of the gamma function for
+// x > 0, by recurrence and the asymptotic series.
+func digamma(x float64) float64 {
+	var r float64
+	for ; x < 6; x++ {
+		r -= 1 / x
+	}
+	f := 1 / (x * x)
+	return r + math.Log(x) - 0.5/x - f*(1.0/12-f*(1.0/120-f*(1.0/252-f*(1.0/240-f/132))))
+}
+
+// dirichletExpectation returns E[log p] of p ~ Dirichlet(alpha).
+func dirichletExpectation(alpha []float64) []float64 {
+	var sum float64
+	for _, a := range alpha {
+		sum += a
+	}
+	e := make([]float64, len(alpha))
+	for i, a := range alpha {
+		e[i] = digamma(a) - digamma(sum)
+	}
+	return e
+}
+
+// sviLocal holds the variational parameters of one document.
+type sviLocal struct {
+	// phi is the responsibility of every word, indexed by sentence and word,
+	// over v*(GlobalK+LocalK)+z for global topic z and
+	// v*(GlobalK+LocalK)+GlobalK+z for local topic z in window s+v.
+	phi [][][]float64
+	// gl is the Dirichlet of the global topics of the document.
+	gl []float64
+	// loc is the Dirichlet of the local topics of every window.
+	loc [][]float64
+	// mixGl and mixLoc are the Beta of global against local per window.
+	mixGl  []float64
+	mixLoc []float64
+	// win is the Dirichlet of the windows of every sentence.
+	win [][]float64
+}
+
+// elogBeta holds E[log phi] of the topics for the words of a mini-batch.
+type elogBeta struct {
+	gl  map[int][]float64
+	loc map[int][]float64
+}
+
+func (s *SVI) elogBeta(docs []Document) *elogBeta {
+	e := &elogBeta{gl: map[int][]float64{}, loc: map[int][]float64{}}
+	rowSums := func(lambda [][]float64) []float64 {
+		sums := make([]float64, len(lambda))
+		for z, row := range lambda {
+			var sum float64
+			for _, l := range row {
+				sum += l
+			}
+			sums[z] = digamma(sum)
+		}
+		return sums
+	}
+	sumGl, sumLoc := rowSums(s.LambdaGl), rowSums(s.LambdaLoc)
+	for _, doc := range docs {
+		for _, sent := range doc.Sentenses {
+			for _, wd := range sent.Words {
+				if _, ok := e.gl[wd]; ok {
+					continue
+				}
+				gl := make([]float64, s.GlobalK)
+				for z := range gl {
+					gl[z] = digamma(s.LambdaGl[z][wd]) - sumGl[z]
+				}
+				loc := make([]float64, s.LocalK)
+				for z := range loc {
+					loc[z] = digamma(s.LambdaLoc[z][wd]) - sumLoc[z]
+				}
+				e.gl[wd], e.loc[wd] = gl, loc
+			}
+		}
+	}
+	return e
+}
+
+// local fits the variational parameters of doc given the topics.
+func (s *SVI) local(doc Document, eb *elogBeta) *sviLocal {
+	t := windowSize(doc, s.T)
+	windows := len(doc.Sentenses) + t
+	k := s.GlobalK + s.LocalK
+	l := &sviLocal{phi: make([][][]float64, len(doc.Sentenses))}
+	for si, sent := range doc.Sentenses {
+		l.phi[si] = make([][]float64, len(sent.Words))
+		for n := range sent.Words {
+			l.phi[si][n] = make([]float64, t*k)
+			for i := range l.phi[si][n] {
+				l.phi[si][n][i] = 1 / float64(t*k)
+			}
+		}
+	}
+
+	iterations := s.Options.LocalIterations
+	if iterations < 1 {
+		iterations = 1
+	}
+	for it := 0; it < iterations; it++ {
+		// the document parameters from the responsibilities
+		l.gl = make([]float64, s.GlobalK)
+		for z := range l.gl {
+			l.gl[z] = s.GlobalAlpha
+		}
+		l.loc = zeros2(windows, s.LocalK)
+		l.mixGl = make([]float64, windows)
+		l.mixLoc = make([]float64, windows)
+		for v := 0; v < windows; v++ {
+			for z := range l.loc[v] {
+				l.loc[v][z] = s.LocalAlpha
+			}
+			l.mixGl[v], l.mixLoc[v] = s.GlobalAlphaMix, s.LocalAlphaMix
+		}
+		l.win = zeros2(len(doc.Sentenses), t)
+		for si, sent := range doc.Sentenses {
+			for v := range l.win[si] {
+				l.win[si][v] = s.Gamma
+			}
+			for n := range sent.Words {
+				p := l.phi[si][n]
+				for v := 0; v < t; v++ {
+					for z := 0; z < s.GlobalK; z++ {
+						q := p[v*k+z]
+						l.gl[z] += q
+						l.mixGl[si+v] += q
+						l.win[si][v] += q
+					}
+					for z := 0; z < s.LocalK; z++ {
+						q := p[v*k+s.GlobalK+z]
+						l.loc[si+v][z] += q
+						l.mixLoc[si+v] += q
+						l.win[si][v] += q
+					}
+				}
+			}
+		}
+		if it == iterations-1 {
+			break
+		}
+
+		// the responsibilities from the document parameters
+		eGl := dirichletExpectation(l.gl)
+		eLoc := make([][]float64, windows)
+		eMixGl := make([]float64, windows)
+		eMixLoc := make([]float64, windows)
+		for v := 0; v < windows; v++ {
+			eLoc[v] = dirichletExpectation(l.loc[v])
+			mix := dirichletExpectation([]float64{l.mixGl[v], l.mixLoc[v]})
+			eMixGl[v], eMixLoc[v] = mix[0], mix[1]
+		}
+		for si, sent := range doc.Sentenses {
+			eWin := dirichletExpectation(l.win[si])
+			for n, wd := range sent.Words {
+				p := l.phi[si][n]
+				for v := 0; v < t; v++ {
+					for z := 0; z < s.GlobalK; z++ {
+						p[v*k+z] = eWin[v] + eMixGl[si+v] + eGl[z] + eb.gl[wd][z]
+					}
+					for z := 0; z < s.LocalK; z++ {
+						p[v*k+s.GlobalK+z] = eWin[v] + eMixLoc[si+v] + eLoc[si+v][z] + eb.loc[wd][z]
+					}
+				}
+				lse := logSumExp(p)
+				for i := range p {
+					p[i] = math.Exp(p[i] - lse)
+				}
+			}
+		}
+	}
+	return l
+}
+
+// Rate returns the learning rate of the next update.
+func (s *SVI) Rate() float64 {
+	return math.Pow(s.Options.Tau0+float64(s.Steps), -s.Options.Kappa)
+}
+
+// Step fits the local parameters of the documents of batch and takes one
+// natural gradient step on the topics towards the estimate from batch
+// scaled to the whole corpus.
+func (s *SVI) Step(batch []Document) {
+	if len(batch) == 0 {
+		return
+	}
+	eb := s.elogBeta(batch)
+	statsGl := map[int][]float64{}
+	statsLoc := map[int][]float64{}
+	k := s.GlobalK + s.LocalK
+	for _, doc := range batch {
+		l := s.local(doc, eb)
+		for si, sent := range doc.Sentenses {
+			for n, wd := range sent.Words {
+				if statsGl[wd] == nil {
+					statsGl[wd] = make([]float64, s.GlobalK)
+					statsLoc[wd] = make([]float64, s.LocalK)
+				}
+				p := l.phi[si][n]
+				for v := 0; v < len(p)/k; v++ {
+					for z := 0; z < s.GlobalK; z++ {
+						statsGl[wd][z] += p[v*k+z]
+					}
+					for z := 0; z < s.LocalK; z++ {
+						statsLoc[wd][z] += p[v*k+s.GlobalK+z]
+					}
+				}
+			}
+		}
+	}
+
+	rho := s.Rate()
+	scale := float64(s.D) / float64(len(batch))
+	update := func(lambda [][]float64, stats map[int][]float64, beta float64) {
+		for z := range lambda {
+			for w := range lambda[z] {
+				target := beta
+				if st, ok := stats[w]; ok {
+					target += scale * st[z]
+				}
+				lambda[z][w] = (1-rho)*lambda[z][w] + rho*target
+			}
+		}
+	}
+	update(s.LambdaGl, statsGl, s.GlobalBeta)
+	update(s.LambdaLoc, statsLoc, s.LocalBeta)
+	s.Steps++
+}
+
+// Train runs epochs passes over the non-holdout documents of docs in
+// shuffled mini-batches.
+func (s *SVI) Train(docs []Document, epochs int) {
+	train := []Document{}
+	for _, doc := range docs {
+		if doc.State != Holdout {
+			train = append(train, doc)
+		}
+	}
+	size := s.Options.BatchSize
+	if size <= 0 {
+		size = len(train)
+	}
+	for e := 0; e < epochs; e++ {
+		perm := s.rng.Perm(len(train))
+		for i := 0; i < len(perm); i += size {
+			batch := []Document{}
+			for j := i; j < i+size && j < len(perm); j++ {
+				batch = append(batch, train[perm[j]])
+			}
+			s.Step(batch)
+		}
+	}
+}
+
+// WordDist returns the posterior mean of the global and local topic word
+// distributions.
+func (s *SVI) WordDist() (*matrix.DenseMatrix, *matrix.DenseMatrix) {
+	normalize := func(lambda [][]float64) *matrix.DenseMatrix {
+		rows := make([][]float64, len(lambda))
+		for z := range lambda {
+			rows[z] = normalizeRow(lambda[z])
+		}
+		return matrix.MakeDenseMatrixStacked(rows)
+	}
+	return normalize(s.LambdaGl), normalize(s.LambdaLoc)
+}
+
+// DocTopicDist fits doc and returns the posterior mean of its global topic
+// distribution and of its local topic distribution pooled over all windows,
+// as MGLDA.DocTopicDist.
+func (s *SVI) DocTopicDist(doc Document) ([]float64, []float64) {
+	l := s.local(doc, s.elogBeta([]Document{doc}))
+	thetaLoc := make([]float64, s.LocalK)
+	for v := range l.loc {
+		for z := range thetaLoc {
+			thetaLoc[z] += l.loc[v][z] - s.LocalAlpha
+		}
+	}
+	for z := range thetaLoc {
+		thetaLoc[z] += s.LocalAlpha
+	}
+	return normalizeRow(l.gl), normalizeRow(thetaLoc)
+}
+
+// Model returns an MGLDA over docs with every word assigned to the window
+// and topic of its largest responsibility under LambdaGl and LambdaLoc.
+// Holdout documents keep random assignments and no counts.
+func (s *SVI) Model(docs *[]Document) *MGLDA {
+	m := newMGLDA(s.GlobalK, s.LocalK, s.Gamma, s.GlobalAlpha, s.LocalAlpha,
+		s.GlobalAlphaMix, s.LocalAlphaMix, s.GlobalBeta, s.LocalBeta, s.T, s.W, docs)
+	k := s.GlobalK + s.LocalK
+	for d, doc := range *docs {
+		m.initDocument(d)
+		if doc.State == Holdout {
+			continue
+		}
+		l := s.local(doc, s.elogBeta([]Document{doc}))
+		for si, sent := range doc.Sentenses {
+			for n := range sent.Words {
+				i := argmax(l.phi[si][n])
+				m.Vdsn[d][si][n] = i / k
+				if z := i % k; z < s.GlobalK {
+					m.Rdsn[d][si][n], m.Zdsn[d][si][n] = globalTopic, z
+				} else {
+					m.Rdsn[d][si][n], m.Zdsn[d][si][n] = localTopic, z-s.GlobalK
+				}
+			}
+		}
+		m.loadDocument(d)
+	}
+	return m
+}
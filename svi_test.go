@@ -0,0 +1,65 @@
+package mglda
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigamma(t *testing.T) {
+	assert.InDelta(t, -0.5772156649015329, digamma(1), 1e-10)
+	assert.InDelta(t, -1.9635100260214235, digamma(0.5), 1e-10)
+	assert.InDelta(t, 2.2517525890667214, digamma(10), 1e-10)
+}
+
+func TestSVI(t *testing.T) {
+	d := reviews(40)
+	opt := SVIOptions{BatchSize: 8, Tau0: 1, Kappa: 0.7, LocalIterations: 10}
+	s := NewSVI(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, len(d), opt, 1)
+	assert.InDelta(t, 1, s.Rate(), 1e-12)
+	s.Train(d, 3)
+	assert.Equal(t, 15, s.Steps)
+	assert.True(t, s.Rate() < 1)
+
+	phiGl, phiLoc := s.WordDist()
+	assert.Equal(t, 6, phiGl.Cols())
+	// word 0 of the good and word 1 of the bad reviews fall apart
+	good, bad := false, false
+	for _, row := range append(phiGl.Arrays(), phiLoc.Arrays()...) {
+		good = good || row[0] > 5*row[1]
+		bad = bad || row[1] > 5*row[0]
+	}
+	assert.True(t, good && bad)
+	for z := 0; z < phiLoc.Rows(); z++ {
+		assert.InDelta(t, 1, sum(phiLoc.RowCopy(z)), 1e-9)
+	}
+
+	gl, loc := s.DocTopicDist(d[0])
+	assert.InDelta(t, 1, sum(gl), 1e-9)
+	assert.InDelta(t, 1, sum(loc), 1e-9)
+
+	// the responsibilities of a word sum to one
+	l := s.local(d[0], s.elogBeta(d[:1]))
+	assert.InDelta(t, 1, sum(l.phi[0][0]), 1e-9)
+	var words float64
+	for v := range l.mixGl {
+		words += l.mixGl[v] + l.mixLoc[v] - 0.2
+	}
+	assert.InDelta(t, float64(d[0].NumberOfWords()), words, 1e-9)
+
+	m := s.Model(&d)
+	var n float64
+	for z := 0; z < m.GlobalK; z++ {
+		n += m.Nglz.Get(z, 0)
+	}
+	for z := 0; z < m.LocalK; z++ {
+		n += m.Nlocz.Get(z, 0)
+	}
+	assert.Equal(t, float64(40*15), n)
+	m.Train(1)
+
+	// the trainer is reproducible from its seed
+	again := NewSVI(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, len(d), opt, 1)
+	again.Train(d, 3)
+	assert.Equal(t, s.LambdaGl, again.LambdaGl)
+}
@@ -0,0 +1,165 @@
+package mglda
+
+import (
+	"math"
+	"sort"
+)
+
+// RankBy selects the score that orders the top words of a topic.
+type RankBy string
+
+const (
+	ByProbability RankBy = "prob"
+	ByRelevance   RankBy = "relevance"
+	ByLift        RankBy = "lift"
+	ByFREX        RankBy = "frex"
+)
+
+// TopWordsOptions configures TopWords.
+type TopWordsOptions struct {
+	// N is the number of words returned per topic.
+	N int
+	// Rank is the score the words are ordered by.
+	Rank RankBy
+	// Lambda weighs probability against lift in the LDAvis relevance
+	// lambda*log(phi) + (1-lambda)*log(lift); 1 ranks by probability.
+	Lambda float64
+	// FREXWeight weighs exclusivity against frequency in FREX.
+	FREXWeight float64
+}
+
+// DefaultTopWordsOptions returns the options used by GetWordTopicDist.
+func DefaultTopWordsOptions() TopWordsOptions {
+	return TopWordsOptions{N: topicLimit, Rank: ByProbability, Lambda: 1, FREXWeight: 0.5}
+}
+
+// TopWord is a ranked word of a topic. Prob is the WordDist probability and
+// Count the number of tokens of the word assigned to the topic. Relevance,
+// Lift and FREX are computed from the beta-smoothed topic word distribution
+// so that they stay finite for unseen words.
+type TopWord struct {
+	Word      int     `json:"id"`
+	Label     string  `json:"word"`
+	Prob      float64 `json:"prob"`
+	Count     int     `json:"count"`
+	Relevance float64 `json:"relevance"`
+	Lift      float64 `json:"lift"`
+	FREX      float64 `json:"frex"`
+}
+
+func (t *TopWord) score(rank RankBy) float64 {
+	switch rank {
+	case ByRelevance:
+		return t.Relevance
+	case ByLift:
+		return t.Lift
+	case ByFREX:
+		return t.FREX
+	}
+	return t.Prob
+}
+
+// ecdf returns for every value the fraction of values that are less than or
+// equal to it.
+func ecdf(values []float64) []float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	result := make([]float64, len(values))
+	for i, v := range values {
+		result[i] = float64(sort.Search(len(sorted), func(j int) bool { return sorted[j] > v })) /
+			float64(len(sorted))
+	}
+	return result
+}
+
+// TopWords returns the top words of every topic of the given kind, ordered
+// by opt.Rank with ties broken by word id. vocabulary may be nil.
+func (m *MGLDA) TopWords(kind TopicKind, vocabulary []string, opt TopWordsOptions) [][]TopWord {
+	phiGl, phiLoc := m.WordDist()
+	phi, nzw, nz, k, beta := phiGl, m.Nglzw, m.Nglz, m.GlobalK, m.GlobalBeta
+	if kind == Local {
+		phi, nzw, nz, k, beta = phiLoc, m.Nloczw, m.Nlocz, m.LocalK, m.LocalBeta
+	}
+
+	// marginal word distribution over all tokens of the corpus
+	pw := make([]float64, m.W)
+	var total float64
+	for w := 0; w < m.W; w++ {
+		for z := 0; z < m.GlobalK; z++ {
+			pw[w] += m.Nglzw.Get(z, w)
+		}
+		for z := 0; z < m.LocalK; z++ {
+			pw[w] += m.Nloczw.Get(z, w)
+		}
+		total += pw[w]
+	}
+	for w := range pw {
+		pw[w] = (pw[w] + beta) / (total + float64(m.W)*beta)
+	}
+
+	smoothed := make([][]float64, k)
+	for z := 0; z < k; z++ {
+		smoothed[z] = make([]float64, m.W)
+		for w := 0; w < m.W; w++ {
+			smoothed[z][w] = (nzw.Get(z, w) + m.beta(kind, z, w)) / (nz.Get(z, 0) + m.betaSum(kind, z))
+		}
+	}
+	exclusivity := make([][]float64, k)
+	for z := range exclusivity {
+		exclusivity[z] = make([]float64, m.W)
+	}
+	for w := 0; w < m.W; w++ {
+		var sum float64
+		for z := 0; z < k; z++ {
+			sum += smoothed[z][w]
+		}
+		for z := 0; z < k; z++ {
+			exclusivity[z][w] = smoothed[z][w] / sum
+		}
+	}
+
+	result := make([][]TopWord, k)
+	for z := 0; z < k; z++ {
+		freqRank := ecdf(smoothed[z])
+		exclRank := ecdf(exclusivity[z])
+		words := make([]TopWord, m.W)
+		for w := 0; w < m.W; w++ {
+			lift := smoothed[z][w] / pw[w]
+			words[w] = TopWord{
+				Word:      w,
+				Label:     wordLabel(vocabulary, w),
+				Prob:      phi.Get(z, w),
+				Count:     int(nzw.Get(z, w)),
+				Relevance: opt.Lambda*math.Log(smoothed[z][w]) + (1-opt.Lambda)*math.Log(lift),
+				Lift:      lift,
+				FREX:      1 / (opt.FREXWeight/exclRank[w] + (1-opt.FREXWeight)/freqRank[w]),
+			}
+		}
+		sort.SliceStable(words, func(a, b int) bool {
+			return words[a].score(opt.Rank) > words[b].score(opt.Rank)
+		})
+		if opt.N < len(words) {
+			words = words[:opt.N]
+		}
+		result[z] = words
+	}
+	return result
+}
+
+// topWordIDs returns the n most probable words of each row of phi.
+func topWordIDs(rows int, row func(i int) []float64, n int) [][]int {
+	top := make([][]int, rows)
+	for i := 0; i < rows; i++ {
+		p := row(i)
+		idx := make([]int, len(p))
+		for j := range idx {
+			idx[j] = j
+		}
+		sort.SliceStable(idx, func(a, b int) bool { return p[idx[a]] > p[idx[b]] })
+		if n < len(idx) {
+			idx = idx[:n]
+		}
+		top[i] = idx
+	}
+	return top
+}
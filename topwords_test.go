@@ -0,0 +1,39 @@
+package mglda
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopWords(t *testing.T) {
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &docs)
+	opt := DefaultTopWordsOptions()
+	opt.N = 5
+	for _, kind := range []TopicKind{Global, Local} {
+		for _, words := range m.TopWords(kind, vocabulary, opt) {
+			assert.Equal(t, 5, len(words))
+			for j := 1; j < len(words); j++ {
+				assert.True(t, words[j-1].Prob >= words[j].Prob)
+				if words[j-1].Prob == words[j].Prob {
+					assert.True(t, words[j-1].Word < words[j].Word)
+				}
+			}
+			for _, word := range words {
+				assert.Equal(t, vocabulary[word.Word], word.Label)
+				assert.True(t, word.Lift > 0)
+				assert.True(t, word.FREX > 0 && word.FREX <= 1)
+			}
+		}
+	}
+
+	opt.Rank = ByRelevance
+	opt.Lambda = 0
+	words := m.TopWords(Global, nil, opt)[0]
+	for j := 1; j < len(words); j++ {
+		assert.True(t, words[j-1].Lift >= words[j].Lift)
+		assert.InDelta(t, math.Log(words[j].Lift), words[j].Relevance, 1e-9)
+	}
+}
@@ -0,0 +1,143 @@
+package mglda
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TimeBucket is the length of the periods a topic trend is counted over.
+type TimeBucket string
+
+const (
+	Day   TimeBucket = "day"
+	Week  TimeBucket = "week"
+	Month TimeBucket = "month"
+	Year  TimeBucket = "year"
+)
+
+// start returns the start of the bucket containing t, in the location of t.
+// Weeks start on Monday.
+func (b TimeBucket) start(t time.Time) (time.Time, error) {
+	y, mo, d := t.Date()
+	switch b {
+	case Day:
+		return time.Date(y, mo, d, 0, 0, 0, 0, t.Location()), nil
+	case Week:
+		return time.Date(y, mo, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location()), nil
+	case Month:
+		return time.Date(y, mo, 1, 0, 0, 0, 0, t.Location()), nil
+	case Year:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, t.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf("trends: unknown time bucket %q", b)
+}
+
+// TrendPoint is the prevalence of a topic in one time bucket: the share of
+// the words of the documents of the bucket assigned to the topic.
+type TrendPoint struct {
+	Start      time.Time `json:"start"`
+	Documents  int       `json:"documents"`
+	Words      int       `json:"words"`
+	Prevalence float64   `json:"prevalence"`
+}
+
+// TopicTrend is the time series of the prevalence of one topic.
+type TopicTrend struct {
+	Kind   TopicKind    `json:"kind"`
+	Topic  int          `json:"topic"`
+	Name   string       `json:"name,omitempty"`
+	Points []TrendPoint `json:"points"`
+}
+
+// TopicTrends returns the prevalence of every global and then every local
+// topic per time bucket, from the counts of the documents with a time.
+// Buckets without documents are left out. The prevalences of all topics in
+// a bucket sum to 1.
+func (m *MGLDA) TopicTrends(bucket TimeBucket) ([]TopicTrend, error) {
+	type counts struct {
+		docs, words int
+		gl, loc     []float64
+	}
+	buckets := map[time.Time]*counts{}
+	for d, doc := range *m.Docs {
+		if doc.State == Holdout || doc.Time == nil {
+			continue
+		}
+		start, err := bucket.start(*doc.Time)
+		if err != nil {
+			return nil, err
+		}
+		c := buckets[start]
+		if c == nil {
+			c = &counts{gl: make([]float64, m.GlobalK), loc: make([]float64, m.LocalK)}
+			buckets[start] = c
+		}
+		c.docs++
+		c.words += doc.NumberOfWords()
+		for z := 0; z < m.GlobalK; z++ {
+			c.gl[z] += m.Ndglz.Get(d, z)
+		}
+		for v := range m.Ndvlocz[d] {
+			for z := 0; z < m.LocalK; z++ {
+				c.loc[z] += m.Ndvlocz[d][v][z]
+			}
+		}
+	}
+	starts := []time.Time{}
+	for start := range buckets {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	trends := []TopicTrend{}
+	for _, kind := range []TopicKind{Global, Local} {
+		k := m.GlobalK
+		if kind == Local {
+			k = m.LocalK
+		}
+		for z := 0; z < k; z++ {
+			trend := TopicTrend{Kind: kind, Topic: z, Name: m.Labels.Name(kind, z), Points: []TrendPoint{}}
+			for _, start := range starts {
+				c := buckets[start]
+				n := c.gl[z]
+				if kind == Local {
+					n = c.loc[z]
+				}
+				p := TrendPoint{Start: start, Documents: c.docs, Words: c.words}
+				if c.words > 0 {
+					p.Prevalence = n / float64(c.words)
+				}
+				trend.Points = append(trend.Points, p)
+			}
+			trends = append(trends, trend)
+		}
+	}
+	return trends, nil
+}
+
+// WriteTrendsCSV writes trends with one row per topic and time bucket,
+// separated by sep (',' for CSV, '\t' for TSV).
+func WriteTrendsCSV(w io.Writer, trends []TopicTrend, sep rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = sep
+	header := []string{"kind", "topic", "name", "start", "documents", "words", "prevalence"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, t := range trends {
+		for _, p := range t.Points {
+			row := []string{string(t.Kind), strconv.Itoa(t.Topic), t.Name, p.Start.Format(time.RFC3339),
+				strconv.Itoa(p.Documents), strconv.Itoa(p.Words),
+				strconv.FormatFloat(p.Prevalence, 'g', -1, 64)}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,55 @@
+package mglda
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicTrends(t *testing.T) {
+	d := reviews(6)
+	for i := range d[:5] {
+		at := time.Date(2020, time.January+time.Month(i/2), 10+i, 12, 0, 0, 0, time.UTC)
+		d[i].Time = &at
+	}
+	m := NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 2, 6, &d)
+	m.Train(2)
+
+	_, err := m.TopicTrends("decade")
+	assert.NotNil(t, err)
+	trends, err := m.TopicTrends(Month)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(trends))
+	assert.Equal(t, 3, len(trends[0].Points))
+	first := trends[0].Points[0]
+	assert.Equal(t, time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC), first.Start)
+	assert.Equal(t, 2, first.Documents)
+	assert.Equal(t, 30, first.Words)
+	for i := range trends[0].Points {
+		var total float64
+		for _, trend := range trends {
+			total += trend.Points[i].Prevalence
+		}
+		assert.InDelta(t, 1, total, 1e-9)
+	}
+
+	// 2020-01-15 is a Wednesday
+	start, err := Week.start(time.Date(2020, time.January, 15, 9, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2020, time.January, 13, 0, 0, 0, 0, time.UTC), start)
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteTrendsCSV(&buf, trends, ','))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 1+4*3, len(lines))
+	assert.True(t, strings.HasPrefix(lines[1], "global,0,,2020-01-01T00:00:00Z,2,30,"))
+
+	buf.Reset()
+	assert.Nil(t, SaveModel(&buf, m, nil))
+	loaded, _, err := LoadModel(&buf)
+	assert.Nil(t, err)
+	assert.True(t, (*loaded.Docs)[0].Time.Equal(*d[0].Time))
+}
@@ -0,0 +1,60 @@
+package mglda
+
+// UpdateOptions configures Update.
+type UpdateOptions struct {
+	// Iteration is the number of sweeps after the new documents are added.
+	Iteration int
+	// OldSample is the fraction of the old active documents that are
+	// resampled along with the new ones; the others are frozen meanwhile.
+	OldSample float64
+}
+
+// Update appends docs to the model as active training documents and
+// trains them incrementally: every word of a new document is assigned in
+// turn by sampling from the current topics, given the words of the document
+// assigned before it, and then opt.Iteration sweeps of Train run over the new
+// documents and a random sample of the old ones. It returns the index of the
+// first new document.
+func (m *MGLDA) Update(docs []Document, opt UpdateOptions) int {
+	first := len(*m.Docs)
+	for _, doc := range docs {
+		doc.State = Active
+		*m.Docs = append(*m.Docs, doc)
+	}
+	m.growDocuments(len(*m.Docs))
+	for d := first; d < len(*m.Docs); d++ {
+		m.initDocument(d)
+		m.initFromTopics(d)
+	}
+	if m.Ratings != nil {
+		m.Ratings.refresh(m)
+	}
+
+	frozen := []int{}
+	for d := 0; d < first; d++ {
+		if (*m.Docs)[d].State == Active && m.random().Float64() >= opt.OldSample {
+			(*m.Docs)[d].State = Frozen
+			frozen = append(frozen, d)
+		}
+	}
+	m.Train(opt.Iteration)
+	for _, d := range frozen {
+		(*m.Docs)[d].State = Active
+	}
+	return first
+}
+
+// initFromTopics assigns the words of document d, which has state but no
+// counts yet, one after the other by sampling from the current counts, and
+// adds them to the counts.
+func (m *MGLDA) initFromTopics(d int) {
+	for s, sent := range (*m.Docs)[d].Sentenses {
+		for w, wd := range sent.Words {
+			v, r, z := m.sample(d, s, wd)
+			m.count(d, s, wd, v, r, z, 1)
+			m.Vdsn[d][s][w] = v
+			m.Rdsn[d][s][w] = r
+			m.Zdsn[d][s][w] = z
+		}
+	}
+}
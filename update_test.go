@@ -0,0 +1,59 @@
+package mglda
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdate(t *testing.T) {
+	d := append([]Document{}, docs...)
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &d)
+	m.Train(2)
+	old := [][]int{}
+	for _, zs := range m.Zdsn[0] {
+		old = append(old, append([]int{}, zs...))
+	}
+	tokens := m.Nglz.Get(0, 0) + m.Nglz.Get(1, 0) + m.Nglz.Get(2, 0) + m.Nglz.Get(3, 0) +
+		m.Nlocz.Get(0, 0) + m.Nlocz.Get(1, 0)
+
+	// without old documents in the sample the old assignments stay put
+	first := m.Update([]Document{docs[0], docs[0]}, UpdateOptions{Iteration: 2})
+	assert.Equal(t, 1, first)
+	assert.Equal(t, 3, len(*m.Docs))
+	assert.Equal(t, 4, len(m.Trace))
+	assert.Equal(t, old, m.Zdsn[0])
+	for _, doc := range *m.Docs {
+		assert.Equal(t, Active, doc.State)
+	}
+
+	n := float64(docs[0].NumberOfWords())
+	assert.Equal(t, tokens+2*n, m.Nglz.Get(0, 0)+m.Nglz.Get(1, 0)+m.Nglz.Get(2, 0)+m.Nglz.Get(3, 0)+
+		m.Nlocz.Get(0, 0)+m.Nlocz.Get(1, 0))
+	assert.Equal(t, 3, m.Ndglz.Rows())
+	for dd := first; dd < len(*m.Docs); dd++ {
+		var nds float64
+		for s := range m.Nds[dd] {
+			nds += m.Nds[dd][s]
+		}
+		assert.Equal(t, n, nds)
+		assert.Equal(t, n, m.Ndgl.Get(dd, 0)+sum(m.Ndvloc[dd]))
+	}
+
+	// the counts of a rebuilt model agree with the incremental ones
+	rebuilt := newMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3, len(vocabulary), m.Docs)
+	for dd := range *m.Docs {
+		rebuilt.initDocument(dd)
+	}
+	rebuilt.Vdsn, rebuilt.Rdsn, rebuilt.Zdsn = m.Vdsn, m.Rdsn, m.Zdsn
+	for dd := range *m.Docs {
+		rebuilt.loadDocument(dd)
+	}
+	assert.Equal(t, rebuilt.Nglzw.Array(), m.Nglzw.Array())
+	assert.Equal(t, rebuilt.Nloczw.Array(), m.Nloczw.Array())
+	assert.Equal(t, rebuilt.Ndvlocz, m.Ndvlocz)
+
+	m.Update(nil, UpdateOptions{Iteration: 1, OldSample: 1})
+	assert.Equal(t, 5, len(m.Trace))
+}
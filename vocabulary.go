@@ -0,0 +1,241 @@
+package mglda
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/skelterjohn/go.matrix"
+)
+
+// ReadVocabulary reads a vocabulary with either one word per line, where the
+// line number (starting from 0) is the word id, or "id<TAB>word" lines. In
+// the latter form the ids must cover 0..len-1 exactly once and blank lines
+// are ignored; in the former a blank line would shift the ids of the words
+// after it and is an error.
+func ReadVocabulary(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r\n"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 || !strings.Contains(lines[0], "\t") {
+		vocabulary := make([]string, len(lines))
+		for i, line := range lines {
+			vocabulary[i] = strings.TrimSpace(line)
+			if vocabulary[i] == "" {
+				return nil, fmt.Errorf("vocabulary: blank line %d", i+1)
+			}
+		}
+		return vocabulary, nil
+	}
+
+	numbered := []string{}
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			numbered = append(numbered, line)
+		}
+	}
+	lines = numbered
+	vocabulary := make([]string, len(lines))
+
+	seen := make([]bool, len(lines))
+	for i, line := range lines {
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("vocabulary: line %d is not id<TAB>word", i+1)
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("vocabulary: invalid id %q in line %d", fields[0], i+1)
+		}
+		if id < 0 || id >= len(lines) {
+			return nil, fmt.Errorf("vocabulary: id %d in line %d out of range", id, i+1)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("vocabulary: duplicate id %d in line %d", id, i+1)
+		}
+		seen[id] = true
+		vocabulary[id] = strings.TrimSpace(fields[1])
+	}
+	return vocabulary, nil
+}
+
+// WriteVocabulary writes a vocabulary with one word per line.
+func WriteVocabulary(w io.Writer, vocabulary []string) error {
+	wt := bufio.NewWriter(w)
+	for _, word := range vocabulary {
+		if _, err := wt.WriteString(word + "\n"); err != nil {
+			return err
+		}
+	}
+	return wt.Flush()
+}
+
+// ValidateDocuments checks that every word id in docs is a valid index into
+// a vocabulary of w words.
+func ValidateDocuments(docs []Document, w int) error {
+	for d, doc := range docs {
+		for s, sent := range doc.Sentenses {
+			for _, wd := range sent.Words {
+				if wd < 0 || wd >= w {
+					return fmt.Errorf("document %d, sentence %d: word id %d out of vocabulary of %d words",
+						d, s, wd, w)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// wordLabel returns the word for id w, or "#w" if the vocabulary does not
+// cover it.
+func wordLabel(vocabulary []string, w int) string {
+	if w >= 0 && w < len(vocabulary) {
+		return vocabulary[w]
+	}
+	return fmt.Sprintf("#%d", w)
+}
+
+// UnknownWord stands for out-of-vocabulary words under the UnknownOOV policy.
+const UnknownWord = "<unk>"
+
+// OOVPolicy tells how words missing from a vocabulary are encoded.
+type OOVPolicy string
+
+const (
+	// SkipOOV drops unknown words.
+	SkipOOV OOVPolicy = "skip"
+	// UnknownOOV maps unknown words to UnknownWord, which is added to the
+	// vocabulary when first needed.
+	UnknownOOV OOVPolicy = "unk"
+	// GrowOOV adds unknown words to the vocabulary.
+	GrowOOV OOVPolicy = "grow"
+)
+
+// Vocabulary maps words to ids and back.
+type Vocabulary struct {
+	Words []string
+	ids   map[string]int
+}
+
+// NewVocabulary returns the vocabulary where words[i] has id i.
+func NewVocabulary(words []string) *Vocabulary {
+	v := &Vocabulary{Words: []string{}, ids: map[string]int{}}
+	for _, word := range words {
+		v.ids[word] = len(v.Words)
+		v.Words = append(v.Words, word)
+	}
+	return v
+}
+
+// Len returns the number of words.
+func (v *Vocabulary) Len() int {
+	return len(v.Words)
+}
+
+// ID returns the id of word.
+func (v *Vocabulary) ID(word string) (int, bool) {
+	id, ok := v.ids[word]
+	return id, ok
+}
+
+// Add returns the id of word, adding it to the vocabulary if it is new.
+func (v *Vocabulary) Add(word string) int {
+	if id, ok := v.ids[word]; ok {
+		return id
+	}
+	v.ids[word] = len(v.Words)
+	v.Words = append(v.Words, word)
+	return len(v.Words) - 1
+}
+
+// Lookup returns the id of word under policy, or false if the word is
+// dropped.
+func (v *Vocabulary) Lookup(word string, policy OOVPolicy) (int, bool) {
+	if id, ok := v.ids[word]; ok {
+		return id, true
+	}
+	switch policy {
+	case UnknownOOV:
+		return v.Add(UnknownWord), true
+	case GrowOOV:
+		return v.Add(word), true
+	}
+	return 0, false
+}
+
+// Encode returns the document of the given sentences of words under policy.
+// Sentences left without words, for example because all of them were
+// dropped, are dropped as well.
+func (v *Vocabulary) Encode(sentences [][]string, policy OOVPolicy) Document {
+	doc := Document{}
+	for _, words := range sentences {
+		sent := Sentense{Words: []int{}}
+		for _, word := range words {
+			if id, ok := v.Lookup(word, policy); ok {
+				sent.Words = append(sent.Words, id)
+			}
+		}
+		if len(sent.Words) > 0 {
+			doc.Sentenses = append(doc.Sentenses, sent)
+		}
+	}
+	return doc
+}
+
+// Encode encodes texts, documents of sentences of words, with v under policy
+// and grows m to the size of v if the policy added words to it.
+func (m *MGLDA) Encode(v *Vocabulary, texts [][][]string, policy OOVPolicy) []Document {
+	docs := make([]Document, len(texts))
+	for i, text := range texts {
+		docs[i] = v.Encode(text, policy)
+	}
+	m.GrowVocabulary(v.Len())
+	return docs
+}
+
+// GrowVocabulary extends m to w words. The new words have no counts, so they
+// only carry the topic word priors; the normalisers of the priors, the seed
+// priors and the rating weights grow with them.
+func (m *MGLDA) GrowVocabulary(w int) {
+	if w <= m.W {
+		return
+	}
+	n := w - m.W
+	var err error
+	if m.Nglzw, err = m.Nglzw.Augment(matrix.Zeros(m.GlobalK, n)); err != nil {
+		panic(err)
+	}
+	if m.Nloczw, err = m.Nloczw.Augment(matrix.Zeros(m.LocalK, n)); err != nil {
+		panic(err)
+	}
+	for _, p := range []struct {
+		seeds *seedPrior
+		beta  float64
+	}{{m.globalSeeds, m.GlobalBeta}, {m.localSeeds, m.LocalBeta}} {
+		if p.seeds == nil {
+			continue
+		}
+		for z := range p.seeds.sum {
+			p.seeds.sum[z] += float64(n) * p.beta
+		}
+	}
+	if rm := m.Ratings; rm != nil {
+		for y := range rm.Common {
+			rm.Common[y] = append(rm.Common[y], make([]float64, n)...)
+		}
+		for a := range rm.Aspect {
+			for y := range rm.Aspect[a] {
+				rm.Aspect[a][y] = append(rm.Aspect[a][y], make([]float64, n)...)
+			}
+		}
+	}
+	m.W = w
+}
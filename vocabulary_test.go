@@ -0,0 +1,87 @@
+package mglda
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadVocabulary(t *testing.T) {
+	v, err := ReadVocabulary(strings.NewReader("company\nmoney\nemail\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"company", "money", "email"}, v)
+	_, err = ReadVocabulary(strings.NewReader("company\nmoney\n\nemail\n"))
+	assert.NotNil(t, err)
+
+	v, err = ReadVocabulary(strings.NewReader("1\tmoney\n\n0\tcompany\n2\temail\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"company", "money", "email"}, v)
+
+	_, err = ReadVocabulary(strings.NewReader("0\tcompany\n0\tmoney\n"))
+	assert.NotNil(t, err)
+	_, err = ReadVocabulary(strings.NewReader("0\tcompany\n5\tmoney\n"))
+	assert.NotNil(t, err)
+}
+
+func TestValidateDocuments(t *testing.T) {
+	assert.Nil(t, ValidateDocuments(docs, len(vocabulary)))
+	assert.NotNil(t, ValidateDocuments(docs, len(vocabulary)-1))
+	assert.Equal(t, "#99", wordLabel(vocabulary, 99))
+}
+
+func TestVocabularyEncode(t *testing.T) {
+	text := [][]string{{"company", "zebra"}, {"zebra"}}
+
+	v := NewVocabulary([]string{"company", "money"})
+	doc := v.Encode(text, SkipOOV)
+	assert.Equal(t, []Sentense{{Words: []int{0}}}, doc.Sentenses)
+	assert.Equal(t, 2, v.Len())
+
+	doc = v.Encode(text, UnknownOOV)
+	assert.Equal(t, []int{0, 2}, doc.Sentenses[0].Words)
+	assert.Equal(t, []int{2}, doc.Sentenses[1].Words)
+	assert.Equal(t, []string{"company", "money", UnknownWord}, v.Words)
+
+	doc = v.Encode(text, GrowOOV)
+	assert.Equal(t, []int{0, 3}, doc.Sentenses[0].Words)
+	id, ok := v.ID("zebra")
+	assert.True(t, ok)
+	assert.Equal(t, 3, id)
+}
+
+func TestGrowVocabulary(t *testing.T) {
+	d := append([]Document{}, docs...)
+	m := NewMGLDA(4, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3,
+		len(vocabulary), &d)
+	assert.Nil(t, m.SetSeeds(&SeedConfig{Boost: 10, Topics: []SeedTopic{
+		{Kind: Local, Topic: 0, Words: []string{"email"}}}}, vocabulary))
+	assert.Nil(t, m.EnableRatings(1, 5, 0.1, 0.01))
+	m.Train(1)
+	ll := m.LogLikelihood()
+
+	v := NewVocabulary(vocabulary)
+	newDocs := m.Encode(v, [][][]string{{{"email", "brandnew"}, {"anothernew"}}}, GrowOOV)
+	assert.Equal(t, len(vocabulary)+2, m.W)
+	assert.Equal(t, m.W, m.Nglzw.Cols())
+	assert.Equal(t, m.W, m.Nloczw.Cols())
+	assert.Equal(t, m.W, len(m.Ratings.Common[0]))
+	assert.InDelta(t, float64(m.W)*0.1+0.9, m.betaSum(Local, 0), 1e-9)
+	assert.InDelta(t, float64(m.W)*0.1, m.betaSum(Local, 1), 1e-9)
+	assert.Nil(t, ValidateDocuments(newDocs, m.W))
+
+	// the new words have no counts, but their prior mass lowers the
+	// likelihood of the old words
+	assert.True(t, m.LogLikelihood() < ll)
+	m.Update(newDocs, UpdateOptions{Iteration: 1})
+	var n float64
+	for z := 0; z < m.GlobalK; z++ {
+		n += m.Nglzw.Get(z, m.W-1)
+	}
+	for z := 0; z < m.LocalK; z++ {
+		n += m.Nloczw.Get(z, m.W-1)
+	}
+	assert.Equal(t, 1.0, n)
+	phiGl, _ := m.WordDist()
+	assert.Equal(t, m.W, phiGl.Cols())
+}
@@ -0,0 +1,269 @@
+package mglda
+
+import (
+	"fmt"
+	"math"
+)
+
+// WindowPrior learns the window size of every document. The size of a
+// document is drawn from Weights, a distribution over the sizes 1..T drawn
+// from a symmetric Dirichlet with parameter Concentration. Both the sizes
+// and Weights are resampled after every sweep.
+type WindowPrior struct {
+	Concentration float64 `json:"concentration"`
+	// Weights holds the prior probability of window size t at t-1.
+	Weights []float64 `json:"weights"`
+}
+
+// windowSize returns the window size of doc in a model with windows of up to
+// t sentences.
+func windowSize(doc Document, t int) int {
+	if doc.Window > 0 {
+		return doc.Window
+	}
+	return t
+}
+
+// window returns the window size of document d.
+func (m *MGLDA) window(d int) int {
+	return windowSize((*m.Docs)[d], m.T)
+}
+
+// ValidateWindows verifies that the window size of every document of docs
+// is at most t.
+func ValidateWindows(docs []Document, t int) error {
+	for d, doc := range docs {
+		if doc.Window < 0 || doc.Window > t {
+			return fmt.Errorf("document %d: window of %d sentences, want 1..%d", d, doc.Window, t)
+		}
+	}
+	return nil
+}
+
+// SetWindows sets the window size of every document of docs without one to
+// size of its number of sentences.
+func SetWindows(docs []Document, size func(sentences int) int) {
+	for i := range docs {
+		if docs[i].Window == 0 {
+			docs[i].Window = size(len(docs[i].Sentenses))
+		}
+	}
+}
+
+// ProportionalWindow returns a window size of ratio times the number of
+// sentences of a document, rounded up and bounded by 1 and t, so that short
+// documents get short windows.
+func ProportionalWindow(ratio float64, t int) func(int) int {
+	return func(sentences int) int {
+		size := int(math.Ceil(ratio * float64(sentences)))
+		if size < 1 {
+			return 1
+		}
+		if size > t {
+			return t
+		}
+		return size
+	}
+}
+
+// LearnWindows makes the window size of every document learned under a
+// uniform initial prior over the sizes 1..T.
+func (m *MGLDA) LearnWindows(concentration float64) error {
+	if concentration <= 0 {
+		return fmt.Errorf("windows: concentration must be positive")
+	}
+	weights := make([]float64, m.T)
+	for t := range weights {
+		weights[t] = 1 / float64(m.T)
+	}
+	m.Windows = &WindowPrior{Concentration: concentration, Weights: weights}
+	return nil
+}
+
+// checkWindowPrior verifies that a loaded window prior matches the T of m.
+func checkWindowPrior(m *MGLDA, p *WindowPrior) error {
+	if p.Concentration <= 0 || len(p.Weights) != m.T {
+		return fmt.Errorf("model: window prior does not match windows of %d sentences", m.T)
+	}
+	return nil
+}
+
+// sampleWindows draws the window size of every active document given its
+// window assignments, and then, unless priors are fixed, the prior weights
+// given the sizes of all counted documents. Only the window distributions
+// of the sentences depend on the size, and a size must cover every assigned
+// window.
+func (m *MGLDA) sampleWindows() {
+	p := m.Windows
+	counts := make([]float64, m.T)
+	for d, doc := range *m.Docs {
+		if doc.State == Holdout {
+			continue
+		}
+		if doc.State == Active {
+			least := 1
+			for s := range m.Vdsn[d] {
+				for _, v := range m.Vdsn[d][s] {
+					if v+1 > least {
+						least = v + 1
+					}
+				}
+			}
+			logp := make([]float64, m.T)
+			for t := range logp {
+				if t+1 < least {
+					logp[t] = math.Inf(-1)
+					continue
+				}
+				logp[t] = math.Log(p.Weights[t])
+				tg := m.gammaSum(t + 1)
+				for s := range m.Nds[d] {
+					a, _ := math.Lgamma(tg)
+					b, _ := math.Lgamma(tg + m.Nds[d][s])
+					logp[t] += a - b
+				}
+			}
+			(*m.Docs)[d].Window = sampleLog(m.random(), logp) + 1
+		}
+		counts[m.window(d)-1]++
+	}
+	if m.fixed {
+		return
+	}
+	for t := range counts {
+		counts[t] += p.Concentration
+	}
+	p.Weights = sampleDirichlet(m.random(), counts)
+}
+
+// sampleLog draws an index with probability proportional to the exponential
+// of logp.
+func sampleLog(r source, logp []float64) int {
+	lse := logSumExp(logp)
+	threshold := r.Float64()
+	var partialSum float64
+	for i, lp := range logp {
+		partialSum += math.Exp(lp - lse)
+		if partialSum >= threshold {
+			return i
+		}
+	}
+	return len(logp) - 1
+}
+
+// gamma returns the prior weight of window v of a sentence, the window
+// ending v sentences after it.
+func (m *MGLDA) gamma(v int) float64 {
+	if m.WindowGamma != nil {
+		return m.WindowGamma[v]
+	}
+	return m.Gamma
+}
+
+// gammaSum returns the sum of the prior weights of the first t windows of a
+// sentence.
+func (m *MGLDA) gammaSum(t int) float64 {
+	if m.WindowGamma == nil {
+		return float64(t) * m.Gamma
+	}
+	var sum float64
+	for _, g := range m.WindowGamma[:t] {
+		sum += g
+	}
+	return sum
+}
+
+// PositionalGamma returns a window prior of the same total weight as the
+// symmetric prior gamma over t windows, in which every window weighs weight
+// times the previous one. Window v of a sentence ends v sentences after it,
+// so a weight above 1 favours the window starting at the sentence.
+func PositionalGamma(gamma, weight float64, t int) []float64 {
+	g := make([]float64, t)
+	for v := range g {
+		g[v] = math.Pow(weight, float64(v))
+	}
+	g = normalizeRow(g)
+	for v := range g {
+		g[v] *= float64(t) * gamma
+	}
+	return g
+}
+
+// SetWindowGamma replaces the symmetric window prior Gamma by gamma, one
+// weight per window of a sentence; nil restores the symmetric prior. With
+// learn, the weights are fitted after every sweep, starting from Gamma if
+// gamma is nil.
+func (m *MGLDA) SetWindowGamma(gamma []float64, learn bool) error {
+	if gamma == nil && learn {
+		gamma = PositionalGamma(m.Gamma, 1, m.T)
+	}
+	if gamma != nil {
+		if len(gamma) != m.T {
+			return fmt.Errorf("windows: %d window weights, want %d", len(gamma), m.T)
+		}
+		for _, g := range gamma {
+			if g <= 0 {
+				return fmt.Errorf("windows: window weights must be positive")
+			}
+		}
+	}
+	m.WindowGamma, m.LearnGamma = gamma, learn
+	return nil
+}
+
+// WindowLogLikelihood returns the log probability of the window assignments
+// of the counted documents under the window prior, with the window
+// distribution of every sentence integrated out. It is the part of the
+// joint likelihood that LogLikelihood, the word likelihood, leaves out; see
+// JointLogLikelihood.
+func (m *MGLDA) WindowLogLikelihood() float64 {
+	var ll float64
+	for d, doc := range *m.Docs {
+		if doc.State == Holdout {
+			continue
+		}
+		t := m.window(d)
+		sum := m.gammaSum(t)
+		for s := range doc.Sentenses {
+			a, _ := math.Lgamma(sum)
+			b, _ := math.Lgamma(sum + m.Nds[d][s])
+			ll += a - b
+			for v := 0; v < t; v++ {
+				a, _ := math.Lgamma(m.Ndsv[d][s][v] + m.gamma(v))
+				b, _ := math.Lgamma(m.gamma(v))
+				ll += a - b
+			}
+		}
+	}
+	return ll
+}
+
+// updateWindowGamma takes one fixed-point step (Minka, 2000) towards the
+// window prior that maximizes WindowLogLikelihood. Every sentence has a
+// Dirichlet over the first windows up to the window size of its document.
+func (m *MGLDA) updateWindowGamma() {
+	num := make([]float64, m.T)
+	den := make([]float64, m.T)
+	for d, doc := range *m.Docs {
+		if doc.State == Holdout {
+			continue
+		}
+		t := m.window(d)
+		sum := m.gammaSum(t)
+		for s := range doc.Sentenses {
+			if m.Nds[d][s] == 0 {
+				continue
+			}
+			ds := digamma(sum+m.Nds[d][s]) - digamma(sum)
+			for v := 0; v < t; v++ {
+				num[v] += digamma(m.Ndsv[d][s][v]+m.gamma(v)) - digamma(m.gamma(v))
+				den[v] += ds
+			}
+		}
+	}
+	for v := range m.WindowGamma {
+		if den[v] > 0 {
+			m.WindowGamma[v] = math.Max(m.WindowGamma[v]*num[v]/den[v], 1e-6)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package mglda
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProportionalWindow(t *testing.T) {
+	size := ProportionalWindow(0.5, 3)
+	assert.Equal(t, 1, size(0))
+	assert.Equal(t, 1, size(1))
+	assert.Equal(t, 2, size(3))
+	assert.Equal(t, 3, size(20))
+
+	d := []Document{{Window: 2}, {Sentenses: make([]Sentense, 5)}}
+	SetWindows(d, size)
+	assert.Equal(t, 2, d[0].Window)
+	assert.Equal(t, 3, d[1].Window)
+	assert.Nil(t, ValidateWindows(d, 3))
+	assert.NotNil(t, ValidateWindows(d, 2))
+}
+
+func TestShortDocuments(t *testing.T) {
+	d := []Document{
+		{},
+		{Sentenses: []Sentense{{}}},
+		{Sentenses: []Sentense{{Words: []int{0, 1, 2}}}, Window: 1},
+		{Sentenses: []Sentense{{Words: []int{3}}, {Words: []int{4, 5}}}, Window: 2},
+		docs[0],
+	}
+	m := NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3, len(vocabulary), &d)
+	m.Train(3)
+	for _, v := range m.Vdsn[2][0] {
+		assert.Equal(t, 0, v)
+	}
+	for s := range m.Vdsn[3] {
+		for _, v := range m.Vdsn[3][s] {
+			assert.True(t, v < 2)
+		}
+	}
+	assert.InDelta(t, 1, sum(m.SentenceLocalDist(2, 0)), 1e-9)
+	gl, loc := m.DocTopicDist(0)
+	assert.InDelta(t, 1, sum(gl), 1e-9)
+	assert.InDelta(t, 1, sum(loc), 1e-9)
+}
+
+func TestLearnWindows(t *testing.T) {
+	d := reviews(20)
+	m := NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3, 6, &d)
+	assert.NotNil(t, m.LearnWindows(0))
+	assert.Nil(t, m.LearnWindows(1))
+	m.Train(10)
+
+	assert.Equal(t, 3, len(m.Windows.Weights))
+	assert.InDelta(t, 1, sum(m.Windows.Weights), 1e-9)
+	for dd, doc := range *m.Docs {
+		assert.True(t, doc.Window >= 1 && doc.Window <= 3)
+		for s := range m.Vdsn[dd] {
+			for _, v := range m.Vdsn[dd][s] {
+				assert.True(t, v < doc.Window)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, SaveModel(&buf, m, nil))
+	loaded, _, err := LoadModel(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, m.Windows, loaded.Windows)
+	assert.Equal(t, *m.Docs, *loaded.Docs)
+	assert.Equal(t, m.Ndsv, loaded.Ndsv)
+}
+
+func TestWindowGamma(t *testing.T) {
+	g := PositionalGamma(0.1, 2, 3)
+	assert.InDelta(t, 0.3, sum(g), 1e-12)
+	assert.InDelta(t, 2*g[0], g[1], 1e-12)
+	assert.Equal(t, []float64{0.1, 0.1, 0.1}, PositionalGamma(0.1, 1, 3))
+
+	d := reviews(20)
+	m := NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3, 6, &d)
+	m.Train(3)
+	symmetric := m.conditional(0, 1, d[0].Sentenses[1].Words[0])
+	ll := m.WindowLogLikelihood()
+	assert.NotNil(t, m.SetWindowGamma([]float64{0.1, 0.1}, false))
+	assert.NotNil(t, m.SetWindowGamma([]float64{0.1, 0, 0.1}, false))
+	assert.Nil(t, m.SetWindowGamma(PositionalGamma(0.1, 1, 3), false))
+	assert.InDeltaSlice(t, symmetric, m.conditional(0, 1, d[0].Sentenses[1].Words[0]), 1e-12)
+	assert.InDelta(t, ll, m.WindowLogLikelihood(), 1e-9)
+
+	// a fitting step does not lower the window likelihood of the counts
+	m.updateWindowGamma()
+	assert.True(t, m.WindowLogLikelihood() >= ll-1e-9)
+
+	assert.Nil(t, m.SetWindowGamma(g, true))
+	m.Train(5)
+	assert.NotEqual(t, PositionalGamma(0.1, 2, 3), m.WindowGamma)
+	var buf bytes.Buffer
+	assert.Nil(t, SaveModel(&buf, m, nil))
+	loaded, _, err := LoadModel(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, m.WindowGamma, loaded.WindowGamma)
+	assert.True(t, loaded.LearnGamma)
+
+	// the traced likelihood includes the window prior
+	assert.InDelta(t, m.LogLikelihood()+m.WindowLogLikelihood(), m.Trace[len(m.Trace)-1], 1e-9)
+	d = reviews(20)
+	m = NewMGLDA(2, 2, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 3, 6, &d)
+	m.Train(1)
+	words, joint := m.LogLikelihood(), m.JointLogLikelihood()
+	assert.Nil(t, m.SetWindowGamma(PositionalGamma(0.1, 4, 3), false))
+	assert.Equal(t, words, m.LogLikelihood())
+	assert.NotEqual(t, joint, m.JointLogLikelihood())
+	m.Train(1)
+	assert.InDelta(t, m.LogLikelihood()+m.WindowLogLikelihood(), m.Trace[1], 1e-9)
+}